@@ -0,0 +1,267 @@
+package latency4go
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+var (
+	ErrProposalNotFound = errors.New("proposal not found")
+)
+
+// DefaultProposalTTL 待审批提案默认的过期时长
+const DefaultProposalTTL = time.Minute * 5
+
+// ProposalStatus 提案状态
+type ProposalStatus string
+
+const (
+	ProposalPending    ProposalStatus = "pending"
+	ProposalApproved   ProposalStatus = "approved"
+	ProposalRejected   ProposalStatus = "rejected"
+	ProposalExpired    ProposalStatus = "expired"
+	ProposalSuperseded ProposalStatus = "superseded"
+	// ProposalFailed 提案已批准但经 reporter 上报失败
+	ProposalFailed ProposalStatus = "failed"
+)
+
+// RankChange 前置排名变化，排名从 1 开始，0 表示新增或移除的前置
+type RankChange struct {
+	Addr string
+	From int
+	To   int
+}
+
+// DiffAddrList 比较两次前置顺序，返回排名变化的前置
+func DiffAddrList(from, to []string) []RankChange {
+	changes := []RankChange{}
+
+	for idx, addr := range to {
+		if rank := slices.Index(from, addr) + 1; rank != idx+1 {
+			changes = append(changes, RankChange{Addr: addr, From: rank, To: idx + 1})
+		}
+	}
+
+	for idx, addr := range from {
+		if !slices.Contains(to, addr) {
+			changes = append(changes, RankChange{Addr: addr, From: idx + 1})
+		}
+	}
+
+	return changes
+}
+
+// Proposal 审批模式下 reporter 待审批的前置顺序
+type Proposal struct {
+	ID       uint64
+	Reporter string
+	Status   ProposalStatus
+	State    *State
+	// Current 提案生成时 reporter 最近一次已上报的前置顺序
+	Current []string
+	Diff    []RankChange
+	Created time.Time
+	Expire  time.Time
+
+	timer *time.Timer
+}
+
+// ProposalNotifier 提案生成及状态变化的通知
+type ProposalNotifier func(*Proposal)
+
+// SetApproval 设置 reporter 的审批模式，ttl 大于 0 时周期上报的新顺序生成待审批提案，
+// 经 Approve 后上报，否则关闭审批模式并丢弃待审批提案
+func (c *LatencyClient) SetApproval(name string, ttl time.Duration) error {
+	if _, exist := c.reporters.Load(name); !exist {
+		return fmt.Errorf(
+			"%w: %s reporter not exists", ErrInvalidReporter, name,
+		)
+	}
+
+	if ttl > 0 {
+		c.approvals.Store(name, ttl)
+		return nil
+	}
+
+	c.approvals.Delete(name)
+	c.closeProposals(name, ProposalRejected)
+
+	return nil
+}
+
+// GetApprovals 审批模式中的 reporter 及提案过期时长
+func (c *LatencyClient) GetApprovals() map[string]time.Duration {
+	approvals := map[string]time.Duration{}
+
+	c.approvals.Range(func(key, value any) bool {
+		approvals[key.(string)] = value.(time.Duration)
+		return true
+	})
+
+	return approvals
+}
+
+// OnProposal 设置提案通知，替换已有的通知
+func (c *LatencyClient) OnProposal(fn ProposalNotifier) {
+	c.proposalFn.Store(&fn)
+}
+
+func (c *LatencyClient) notifyProposal(p *Proposal) {
+	slog.Info(
+		"latency proposal status",
+		slog.Uint64("id", p.ID),
+		slog.String("reporter", p.Reporter),
+		slog.String("status", string(p.Status)),
+		slog.Any("addr", p.State.AddrList),
+	)
+
+	if fn := c.proposalFn.Load(); fn != nil && *fn != nil {
+		(*fn)(p)
+	}
+}
+
+// propose 审批模式下以提案代替上报，返回 reporter 是否处于审批模式
+func (c *LatencyClient) propose(name string, state *State) bool {
+	v, exist := c.approvals.Load(name)
+	if !exist {
+		return false
+	}
+	ttl := v.(time.Duration)
+
+	current := []string{}
+	if history := c.GetHistory(name); len(history) > 0 {
+		current = history[len(history)-1].AddrList
+	}
+
+	if slices.Equal(current, state.AddrList) {
+		c.closeProposals(name, ProposalSuperseded)
+		return true
+	}
+
+	// 与待审批提案相同的顺序不重复生成提案
+	for _, p := range c.GetProposals() {
+		if p.Reporter == name && slices.Equal(p.State.AddrList, state.AddrList) {
+			return true
+		}
+	}
+
+	c.closeProposals(name, ProposalSuperseded)
+
+	now := time.Now()
+	p := &Proposal{
+		ID:       c.proposalSeq.Add(1),
+		Reporter: name,
+		Status:   ProposalPending,
+		State:    state,
+		Current:  current,
+		Diff:     DiffAddrList(current, state.AddrList),
+		Created:  now,
+		Expire:   now.Add(ttl),
+	}
+
+	c.proposalLock.Lock()
+	if c.proposals == nil {
+		c.proposals = map[uint64]*Proposal{}
+	}
+	c.proposals[p.ID] = p
+	p.timer = time.AfterFunc(ttl, func() {
+		c.closeProposal(p.ID, ProposalExpired)
+	})
+	c.proposalLock.Unlock()
+
+	c.notifyProposal(p)
+
+	return true
+}
+
+// takeProposal 移除待审批提案并停止过期计时，不发送通知
+func (c *LatencyClient) takeProposal(id uint64) (*Proposal, error) {
+	c.proposalLock.Lock()
+	defer c.proposalLock.Unlock()
+
+	p, exist := c.proposals[id]
+	if !exist {
+		return nil, fmt.Errorf("%w: %d", ErrProposalNotFound, id)
+	}
+
+	delete(c.proposals, id)
+	p.timer.Stop()
+
+	return p, nil
+}
+
+// finishProposal 以 status 通知已移除的提案，返回结束后的提案
+func (c *LatencyClient) finishProposal(p *Proposal, status ProposalStatus) *Proposal {
+	closed := *p
+	closed.Status = status
+	c.notifyProposal(&closed)
+
+	return &closed
+}
+
+// closeProposal 结束待审批提案，返回结束后的提案
+func (c *LatencyClient) closeProposal(id uint64, status ProposalStatus) (*Proposal, error) {
+	p, err := c.takeProposal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.finishProposal(p, status), nil
+}
+
+func (c *LatencyClient) closeProposals(name string, status ProposalStatus) {
+	for _, p := range c.GetProposals() {
+		if p.Reporter == name {
+			c.closeProposal(p.ID, status)
+		}
+	}
+}
+
+// GetProposals 待审批的提案，按 ID 升序
+func (c *LatencyClient) GetProposals() []*Proposal {
+	c.proposalLock.Lock()
+	defer c.proposalLock.Unlock()
+
+	proposals := make([]*Proposal, 0, len(c.proposals))
+	for _, p := range c.proposals {
+		proposals = append(proposals, p)
+	}
+
+	slices.SortFunc(proposals, func(a, b *Proposal) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return proposals
+}
+
+// Approve 批准提案并经 reporter 上报提案中的前置顺序，
+// 上报成功后通知提案已批准，否则通知提案失败
+func (c *LatencyClient) Approve(id uint64) (*Proposal, error) {
+	p, err := c.takeProposal(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if v, exist := c.reporters.Load(p.Reporter); !exist {
+		err = fmt.Errorf(
+			"%w: %s reporter not exists", ErrInvalidReporter, p.Reporter,
+		)
+	} else {
+		err = c.report(p.Reporter, v.(Reporter), p.State)
+	}
+
+	if err != nil {
+		return c.finishProposal(p, ProposalFailed), err
+	}
+
+	return c.finishProposal(p, ProposalApproved), nil
+}
+
+// Reject 拒绝提案，reporter 保持当前顺序
+func (c *LatencyClient) Reject(id uint64) (*Proposal, error) {
+	return c.closeProposal(id, ProposalRejected)
+}
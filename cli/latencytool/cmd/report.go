@@ -152,6 +152,15 @@ trading systems specified by args.`,
 				return err
 			}
 
+			// 审批模式需在插件 reporter 注册后设置
+			approvals, _ := cmd.Flags().GetStringSlice("approval")
+			approvalTTL, _ := cmd.Flags().GetDuration("approval-ttl")
+			for _, name := range approvals {
+				if err := ins.SetApproval(name, approvalTTL); err != nil {
+					return errors.Join(errInvalidArgs, err)
+				}
+			}
+
 			if err := ins.Start(interval); err != nil {
 				return err
 			}
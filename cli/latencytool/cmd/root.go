@@ -16,6 +16,7 @@ import (
 	"strings"
 	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -32,6 +33,7 @@ var (
 	logFile                                   string
 	logSize                                   int
 	logKeep                                   int
+	logTee                                    *ctl.LogHandler
 
 	cmdCtx    context.Context
 	cmdCancel context.CancelFunc
@@ -124,34 +126,126 @@ func consoleExecute(
 		}
 	case "plugin":
 	case "unplugin":
+	case "audit":
+	case "sessions":
+	case "kick":
+		remote, _ := cmdFlags.GetString("remote")
+		if remote == "" {
+			return errors.Join(
+				errInvalidArgs,
+				errors.New("no session remote specified"),
+			)
+		}
+		execute.KwArgs["remote"] = remote
+
+		if handler, _ := cmdFlags.GetString("handler"); handler != "" {
+			execute.KwArgs["handler"] = handler
+		}
+	case "seats", "priority":
+		plugin, _ := cmdFlags.GetString("plugin")
+		if plugin == "" {
+			return errors.Join(
+				errInvalidArgs,
+				errors.New("no plugin specified"),
+			)
+		}
+		execute.KwArgs["plugin"] = plugin
+	case "push":
+		for _, name := range []string{"addrs", "order", "plugin", "until"} {
+			if cmdFlags.Changed(name) {
+				execute.KwArgs[name] = cmdFlags.Lookup(name).Value.String()
+			}
+		}
+	case "unpin":
+		if plugin, _ := cmdFlags.GetString("plugin"); plugin != "" {
+			execute.KwArgs["plugin"] = plugin
+		}
+	case "history":
+		if plugin, _ := cmdFlags.GetString("plugin"); plugin != "" {
+			execute.KwArgs["plugin"] = plugin
+		}
+	case "rollback":
+		for _, name := range []string{"steps", "plugin", "hold"} {
+			if cmdFlags.Changed(name) {
+				execute.KwArgs[name] = cmdFlags.Lookup(name).Value.String()
+			}
+		}
+	case "approval":
+		plugin, _ := cmdFlags.GetString("plugin")
+		if plugin == "" {
+			return errors.Join(
+				errInvalidArgs,
+				errors.New("no plugin specified"),
+			)
+		}
+		execute.KwArgs["plugin"] = plugin
+
+		if cmdFlags.Changed("approval-ttl") {
+			ttl, _ := cmdFlags.GetDuration("approval-ttl")
+			execute.KwArgs["ttl"] = ttl.String()
+		}
+	case "proposals":
+	case "approve", "reject":
+		if !cmdFlags.Changed("id") {
+			return errors.Join(
+				errInvalidArgs,
+				errors.New("no proposal id specified"),
+			)
+		}
+		id, _ := cmdFlags.GetUint64("id")
+		execute.KwArgs["id"] = strconv.FormatUint(id, 10)
+	case "schema":
 	default:
-		return errors.New("unsupported command")
+		// 自定义命令参数以 --kwarg 传递，由服务端按命令定义校验
+		if !client.GetServerHello().Supports(command) {
+			return errors.New("unsupported command")
+		}
 	}
 
-	cmdWait := make(chan struct{})
+	if cmdFlags.Changed("kwarg") {
+		kwargs, _ := cmdFlags.GetStringToString("kwarg")
+		for k, v := range kwargs {
+			execute.KwArgs[k] = v
+		}
+	}
 
+	// 命令结果由 Call 返回，消息循环仅处理广播
 	client.MessageLoop(
 		"console loop",
 		nil, nil,
-		func(r *ctl.Result) error {
-			defer func() {
-				if r.CmdName == command {
-					close(cmdWait)
-				}
-			}()
-
-			return ctl.LogResult(r)
-		},
+		func(r *ctl.Result) error { return nil },
 		nil,
 	)
 
-	if err := client.Command(&execute); err != nil {
-		return err
+	client.ProgressLoop("console progress", func(p *ctl.Progress) error {
+		slog.Info(
+			"command in progress",
+			slog.String("cmd", p.CmdName),
+			slog.String("stage", p.Stage),
+			slog.String("message", p.Message),
+			slog.Duration("elapsed", p.Elapsed),
+		)
+		return nil
+	})
+
+	if timeout, _ := cmdFlags.GetDuration("timeout"); timeout > 0 {
+		// 服务端执行超时与等待结果的超时保持一致
+		if cmdFlags.Changed("timeout") &&
+			client.GetServerHello().Supports("cancel") {
+			execute.KwArgs["timeout"] = timeout.String()
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
 	}
 
-	<-cmdWait
+	result, err := client.Call(ctx, &execute)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return ctl.LogResult(result)
 }
 
 // rootCmd represents the base command when called without any subcommands
@@ -187,6 +281,14 @@ and report to trading systems`,
 				client, err = ctl.NewCtlTcpClient(
 					strings.TrimPrefix(clientConn, "tcp://"),
 				)
+			case strings.HasPrefix(clientConn, "unix://"):
+				client, err = ctl.NewCtlUnixClient(
+					strings.TrimPrefix(clientConn, "unix://"),
+				)
+			case strings.HasPrefix(clientConn, "tls://"):
+				client, err = ctl.NewCtlTlsClient(
+					strings.TrimPrefix(clientConn, "tls://"),
+				)
 			case strings.HasPrefix(clientConn, "ssh+tcp://"):
 				client, err = ctl.NewCtlSshTcpClient(
 					strings.TrimPrefix(clientConn, "ssh+tcp://"),
@@ -204,8 +306,10 @@ and report to trading systems`,
 					cmdCtx, client, reportCmd.Flags(), client.Release,
 				)
 			} else {
+				// 控制台参数定义于根命令，--interval 等运行参数为根命令的持久参数，
+				// 不可使用 reportCmd 中同名但未经解析的本地参数
 				err = consoleExecute(
-					cmdCtx, client, reportCmd.Flags(), client.Release,
+					cmdCtx, client, cmd.Flags(), client.Release,
 				)
 			}
 
@@ -228,6 +332,8 @@ and report to trading systems`,
 		sink, _ := cmd.Flags().GetString("sink")
 
 		ins := latency4go.LatencyClient{}
+		historySize, _ := cmd.Flags().GetInt("history")
+		ins.SetHistorySize(historySize)
 
 		if err := ins.Init(
 			cmdCtx, schema, host, port, sink, &config,
@@ -239,13 +345,27 @@ and report to trading systems`,
 
 		ctlConns, _ := cmd.Flags().GetStringSlice("ctl")
 		if len(ctlConns) > 0 {
-			cfg := &ctl.CtlSvrHdlConfig{}
+			auditPath, _ := cmd.Flags().GetString("audit")
+			hold, _ := cmd.Flags().GetDuration("hold")
+			approvals, _ := cmd.Flags().GetStringSlice("approval")
+			approvalTTL, _ := cmd.Flags().GetDuration("approval-ttl")
+			cfg := (&ctl.CtlSvrHdlConfig{}).Audit(
+				auditPath,
+			).Logs(logTee).Rollback(
+				historySize, hold,
+			).Approval(approvalTTL, approvals...)
 			for _, conn := range ctlConns {
 				switch {
 				case strings.HasPrefix(conn, "ipc://"):
 					cfg = cfg.Ipc(conn)
 				case strings.HasPrefix(conn, "tcp://"):
 					cfg = cfg.Tcp(conn)
+				case strings.HasPrefix(conn, "tls://"):
+					cfg = cfg.Tls(conn)
+				case strings.HasPrefix(conn, "unix://"):
+					cfg = cfg.Unix(conn)
+				case strings.HasPrefix(conn, "http://"):
+					cfg = cfg.Http(conn)
 				default:
 					cfg = cfg.Ipc(conn)
 				}
@@ -353,12 +473,15 @@ func initLog() {
 		addSource = true
 	}
 
-	slog.SetDefault(slog.New(slog.NewTextHandler(
+	// 控制台服务端日志经由分流器广播至订阅 log 主题的客户端
+	logTee = ctl.NewLogHandler(slog.NewTextHandler(
 		logWr, &slog.HandlerOptions{
 			AddSource: addSource,
 			Level:     level,
 		},
-	)))
+	))
+
+	slog.SetDefault(slog.New(logTee))
 
 	slog.Debug("logger initiated")
 }
@@ -371,6 +494,14 @@ func init() {
 		cmd.Version = rootCmd.Version
 	}
 
+	// 控制台握手时向对端发送的构建版本
+	if version != "" {
+		ctl.BuildVersion = version
+		if gitVersion != "" {
+			ctl.BuildVersion += "+" + gitVersion
+		}
+	}
+
 	// signal handler for global context
 	cmdCtx, cmdCancel = signal.NotifyContext(
 		context.Background(),
@@ -442,6 +573,23 @@ func init() {
 	rootCmd.PersistentFlags().StringSlice(
 		"ctl", nil, "Control service listen string",
 	)
+	rootCmd.PersistentFlags().String(
+		"audit", "", "Control service command audit log path",
+	)
+	rootCmd.PersistentFlags().Int(
+		"history", latency4go.DefaultHistorySize,
+		"Reported states kept per plugin for rollback",
+	)
+	rootCmd.PersistentFlags().Duration(
+		"hold", time.Minute*10, "Periodic report hold period after rollback",
+	)
+	rootCmd.PersistentFlags().StringSlice(
+		"approval", nil, "Plugins require approval for priority changes",
+	)
+	rootCmd.PersistentFlags().Duration(
+		"approval-ttl", latency4go.DefaultProposalTTL,
+		"Pending priority proposal expire duration",
+	)
 	rootCmd.Flags().String(
 		"conn", "", "Control service connect string",
 	)
@@ -451,6 +599,36 @@ func init() {
 	rootCmd.Flags().String(
 		"cmd", "", "Command for ctl server handle",
 	)
+	rootCmd.Flags().Duration(
+		"timeout", time.Second*30, "Command result wait timeout",
+	)
+	rootCmd.Flags().String(
+		"remote", "", "Session remote address for kick command",
+	)
+	rootCmd.Flags().String(
+		"handler", "", "Session handler for kick command",
+	)
+	rootCmd.Flags().String(
+		"plugin", "", "Target plugin name for plugin related commands",
+	)
+	rootCmd.Flags().String(
+		"addrs", "", "Front addresses in priority order for push command",
+	)
+	rootCmd.Flags().String(
+		"order", "", "Reorder current state by ranks for push command",
+	)
+	rootCmd.Flags().String(
+		"until", "", "Pin plugins until time(RFC3339) or duration for push command",
+	)
+	rootCmd.Flags().Int(
+		"steps", 1, "Steps back from last reported state for rollback command",
+	)
+	rootCmd.Flags().Uint64(
+		"id", 0, "Proposal id for approve & reject command",
+	)
+	rootCmd.Flags().StringToString(
+		"kwarg", nil, "Extra command args, e.g. --kwarg name=value",
+	)
 
 	for _, cmd := range rootCmd.Commands() {
 		cmd.Version = rootCmd.Version
@@ -1,10 +1,12 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/frozenpine/latency4go/ctl"
 	"github.com/gdamore/tcell/v2"
@@ -15,81 +17,25 @@ import (
 var (
 	commandView = tview.NewInputField()
 
-	commandHelp = `════════════════════════════════════════════════════
+	commandHelpHeader = `════════════════════════════════════════════════════
  Available commands:
 ──────────────── Remote Commands ───────────────────
-    start: start latency client
-	 stop: stop latency client
-  suspend: suspend latency client running
-   resume: resume suspended latency client
-   period: change latency client query period
-    state: get latency client last state
-   config: change latency client query config
-    query: query latency result with onetime config
-   plugin: add latency reporter plugin
- unplugin: remove reporter plugin from latency client
-     info: get latency client info
-──────────────── Local Commands ────────────────────
-     help: print this help message
- 	  top: change TopK view
- 	 exit: exit ctl client running
-════════════════════════════════════════════════════
 `
-
-	startDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > start ↵
-═══════════════════════════════════════════════════════════════════════════════
+	commandHelpLocal = `──────────────── Local Commands ────────────────────
+       logs: filter server log pane
+       help: print this help message
+        top: change TopK view
+       exit: exit ctl client running
+     F2/F3: focus proposal panel / info tree, Esc back to command
+              proposal panel: a approve, r reject selected proposal
+════════════════════════════════════════════════════
 `
 
-	stopDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > stop ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
+	detailSeparator = "═══════════════════════════════════════════════════════════════════════════════\n"
 
-	suspendDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > suspend ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	resumeDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > resume ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	periodDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > period --interval {duration} ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	stateDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > state ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	configDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > config [--before {duration}] 
-                  [--range {from=YYYY-mm-ddTHH:MM:SS[,to=YYYY-mm-ddTHH:MM:SS]}]
-                  [--from {pico sec}] [--to {pico sec}] 
-				  [--agg {result count}] [--least {agg least count}]
-		          [--sort {parmas.(mid|avg|stdev|sample_stdev) +-*/ ...}]
-		          [--user {client_id}]+ [--percents {quantile}]+ ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	queryDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > query [--before {duration}] 
-                 [--range {from=YYYY-mm-ddTHH:MM:SS[,to=YYYY-mm-ddTHH:MM:SS]}]
-                 [--from {pico sec}] [--to {pico sec}] 
-				 [--agg {result count}] [--least {agg least count}]
-		         [--sort {parmas.(mid|avg|stdev|sample_stdev) +-*/ ...}]
-		         [--user {client_id}]+ [--percents {quantile}]+ ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	pluginDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > plugin --name {plugin_name} --config {plugin_name}={config_file} ↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	unpluginDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > unplugin --name {plugin_name}	↵
-═══════════════════════════════════════════════════════════════════════════════
-`
-	showDetail = `═══════════════════════════════════════════════════════════════════════════════
- Commnad > show {something} ↵
+	logsDetail = `═══════════════════════════════════════════════════════════════════════════════
+ Commnad > logs {DEBUG|INFO|WARN|ERROR} [keyword] ↵
+ Commnad > logs off ↵
 ═══════════════════════════════════════════════════════════════════════════════
 `
 	topDetail = `═══════════════════════════════════════════════════════════════════════════════
@@ -105,27 +51,49 @@ var (
 ═══════════════════════════════════════════════════════════════════════════════
 `
 
+	// commandDetails 本地命令的帮助，远程命令的帮助由命令定义生成
 	commandDetails = map[string]string{
-		"start":    startDetail,
-		"stop":     stopDetail,
-		"suspend":  suspendDetail,
-		"resume":   resumeDetail,
-		"period":   periodDetail,
-		"state":    stateDetail,
-		"config":   configDetail,
-		"query":    queryDetail,
-		"plugin":   pluginDetail,
-		"unplugin": unpluginDetail,
-		"show":     showDetail,
-		"help":     helpDetail,
-		"top":      topDetail,
-		"exit":     exitDetail,
+		"logs": logsDetail,
+		"help": helpDetail,
+		"top":  topDetail,
+		"exit": exitDetail,
 	}
 
+	commandTimeout = time.Second * 30
+
 	commandHistory = []string{}
 	commandHisIdx  = 0
 )
 
+// callCommand 异步调用命令，结果按 msgID 返回后更新界面，同名命令并发执行时互不干扰
+func callCommand(client ctl.CtlClient, cmd *ctl.Command) {
+	go func() {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), commandTimeout,
+		)
+		defer cancel()
+
+		result, err := client.Call(ctx, cmd)
+		if err != nil {
+			slog.Error(
+				"call command failed",
+				slog.Any("error", err),
+				slog.String("cmd", cmd.Name),
+				slog.Any("kwargs", cmd.KwArgs),
+			)
+			return
+		}
+
+		if err := handleResult(result); err != nil {
+			slog.Error(
+				"handle command result failed",
+				slog.Any("error", err),
+				slog.String("cmd", cmd.Name),
+			)
+		}
+	}()
+}
+
 func init() {
 	commandView.SetLabel(
 		"Command > ",
@@ -150,8 +118,14 @@ func init() {
 		commands := strings.Split(inputCommand, " ")
 		kwargs := map[string]string{}
 
+		cmdName := commands[0]
 		cmdFlags := (*client.flags)
 
+		// 远程命令按命令定义解析参数
+		if spec := getCommandSpec(cmdName); spec != nil {
+			cmdFlags = *schemaFlags(spec)
+		}
+
 		if err := cmdFlags.ParseAll(
 			commands[1:],
 			func(flag *pflag.Flag, value string) error {
@@ -171,33 +145,54 @@ func init() {
 			return
 		}
 
-		switch commands[0] {
-		case "stop":
-		case "start":
-		case "suspend":
-		case "resume":
-		case "period":
-		case "state":
-		case "config":
-		case "query":
-		case "info":
-		case "plugin":
-		case "unplugin":
-		case "help":
-			helpCmd := cmdFlags.Arg(0)
-			if helpCmd == "" {
-				logView.Write([]byte(commandHelp))
-			} else {
-				if cmdDetail, exists := commandDetails[helpCmd]; exists {
-					logView.Write([]byte(cmdDetail))
+		switch cmdName {
+		case "subscribe", "unsubscribe":
+			kwargs["topic"] = cmdFlags.Arg(0)
+
+			for _, arg := range cmdFlags.Args()[min(1, cmdFlags.NArg()):] {
+				if arg == "changed" {
+					kwargs["changed"] = "true"
 				} else {
+					kwargs["k"] = arg
+				}
+			}
+		case "logs":
+			kwargs["topic"] = string(ctl.TopicLog)
+
+			if cmdFlags.Arg(0) == "off" {
+				cmdName = "unsubscribe"
+				break
+			}
+
+			level := slog.LevelInfo
+			if v := cmdFlags.Arg(0); v != "" {
+				if err := level.UnmarshalText([]byte(v)); err != nil {
 					slog.Error(
-						"no command detail help found",
-						slog.String("cmd", helpCmd),
+						"invalid server log level",
+						slog.Any("error", err),
 					)
-					logView.Write([]byte(commandHelp))
+					return
 				}
 			}
+
+			cmdName = "subscribe"
+			kwargs["level"] = level.String()
+			SetServerLogFilter(level, cmdFlags.Arg(1))
+		case "help":
+			helpCmd := cmdFlags.Arg(0)
+			if helpCmd == "" {
+				logView.Write([]byte(commandHelpText()))
+			} else if cmdDetail, exists := commandDetails[helpCmd]; exists {
+				logView.Write([]byte(cmdDetail))
+			} else if spec := getCommandSpec(helpCmd); spec != nil {
+				logView.Write([]byte(commandDetailText(spec)))
+			} else {
+				slog.Error(
+					"no command detail help found",
+					slog.String("cmd", helpCmd),
+				)
+				logView.Write([]byte(commandHelpText()))
+			}
 			goto END
 		case "top":
 			v := cmdFlags.Arg(0)
@@ -214,25 +209,44 @@ func init() {
 			client.cancel()
 			return
 		default:
-			slog.Error(
-				"unsupported command",
-				slog.String("cmd", commands[0]),
-				slog.Any("args", commands[1:]),
-			)
-			return
+			spec := getCommandSpec(cmdName)
+			if spec == nil {
+				slog.Error(
+					"unsupported command",
+					slog.String("cmd", commands[0]),
+					slog.Any("args", commands[1:]),
+				)
+				return
+			}
+
+			// 位置参数按命令定义依次对应
+			positionals := spec.Positionals()
+			if cmdFlags.NArg() > len(positionals) {
+				slog.Error(
+					"too many command args",
+					slog.String("usage", spec.Usage()),
+				)
+				return
+			}
+
+			for idx, arg := range cmdFlags.Args() {
+				kwargs[positionals[idx].Name] = arg
+			}
+
+			if err := spec.Validate(kwargs); err != nil {
+				slog.Error(
+					"invalid command args",
+					slog.Any("error", err),
+					slog.String("usage", spec.Usage()),
+				)
+				return
+			}
 		}
 
-		if err := client.client.Command(&ctl.Command{
-			Name:   commands[0],
+		callCommand(client.client, &ctl.Command{
+			Name:   cmdName,
 			KwArgs: kwargs,
-		}); err != nil {
-			slog.Error(
-				"send command failed",
-				slog.Any("error", err),
-				slog.String("cmd", commands[0]),
-				slog.Any("args", commands[1:]),
-			)
-		}
+		})
 
 	END:
 		commandHistory = append(commandHistory, inputCommand)
@@ -253,6 +267,13 @@ func init() {
 
 	commandView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
+		case tcell.KeyTab:
+			text, candidates := completeCommand(commandView.GetText())
+			if len(candidates) > 1 {
+				logView.Write([]byte(strings.Join(candidates, "  ") + "\n"))
+			}
+			commandView.SetText(text)
+			return nil
 		case tcell.KeyUp:
 			commandHisIdx--
 			if commandHisIdx < 0 {
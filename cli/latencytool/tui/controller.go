@@ -1,9 +1,11 @@
 package tui
 
 import (
+	"context"
 	"fmt"
 	"time"
 
+	"github.com/frozenpine/latency4go/ctl"
 	"github.com/frozenpine/latency4go/libs"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
@@ -28,6 +30,54 @@ func SetInterval(dur time.Duration) {
 	}
 }
 
+func SetConnState(state ctl.ConnState) {
+	if client := instance.Load(); client != nil {
+		var color string
+		switch state {
+		case ctl.ConnConnected:
+			color = "green"
+		case ctl.ConnReconnecting:
+			color = "orange"
+		default:
+			color = "red"
+		}
+
+		title := fmt.Sprintf(" Ctl Server Info [%s]%s[white] ", color, state)
+		if hello := client.client.GetServerHello(); hello != nil {
+			title += fmt.Sprintf("v[orange]%d[white] ", hello.Version)
+			if hello.Build != "" {
+				title += fmt.Sprintf("[orange]%s[white] ", tview.Escape(hello.Build))
+			}
+		}
+		if rtt := client.client.GetRTT(); rtt > 0 && state == ctl.ConnConnected {
+			title += fmt.Sprintf("RTT [orange]%s[white] ", rtt.Round(time.Microsecond))
+		}
+
+		client.app.Lock()
+		ctlSvrView.SetTitle(title)
+		client.app.Unlock()
+
+		client.app.Draw()
+	}
+}
+
+// watchRTT 周期性刷新连接状态及心跳往返时延
+func watchRTT(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if client := instance.Load(); client != nil {
+				SetConnState(client.client.GetConnState())
+			}
+		}
+	}
+}
+
 func SetSummary(values []string) {
 	if client := instance.Load(); client != nil {
 		client.app.Lock()
@@ -52,7 +102,9 @@ func SetPlugins(plugins []*libs.PluginContainer) {
 			pluginNode.AddChild(
 				tview.NewTreeNode(
 					p.String(),
-				),
+				).SetReference(
+					p,
+				).SetSelectable(true).Collapse(),
 			)
 		}
 		pluginNode.Expand()
@@ -62,6 +114,107 @@ func SetPlugins(plugins []*libs.PluginContainer) {
 	}
 }
 
+// findPluginNode 按插件名称查找插件节点，需在持有 app 锁时调用
+func findPluginNode(name string) *tview.TreeNode {
+	for _, node := range pluginNode.GetChildren() {
+		if p, ok := node.GetReference().(*libs.PluginContainer); ok && p.Name() == name {
+			return node
+		}
+	}
+
+	return nil
+}
+
+// SetPins 标记手动推送后锁定中的插件
+func SetPins(pins map[string]time.Time) {
+	if client := instance.Load(); client != nil {
+		client.app.Lock()
+		for _, node := range pluginNode.GetChildren() {
+			p, ok := node.GetReference().(*libs.PluginContainer)
+			if !ok {
+				continue
+			}
+
+			if until, pinned := pins[p.Name()]; pinned {
+				node.SetText(fmt.Sprintf(
+					"%s pinned until %s", p.String(), until.Format(time.TimeOnly),
+				)).SetColor(tcell.ColorOrangeRed)
+			} else {
+				node.SetText(p.String()).SetColor(tview.Styles.PrimaryTextColor)
+			}
+		}
+		client.app.Unlock()
+
+		client.app.Draw()
+	}
+}
+
+// setPluginChild 替换插件节点下指定标题的子节点
+func setPluginChild(name, title string, items []string) {
+	if client := instance.Load(); client != nil {
+		client.app.Lock()
+		if node := findPluginNode(name); node != nil {
+			for _, child := range node.GetChildren() {
+				if child.GetText() == title {
+					node.RemoveChild(child)
+				}
+			}
+
+			child := tview.NewTreeNode(title).SetColor(tcell.ColorDarkCyan)
+			for _, item := range items {
+				child.AddChild(tview.NewTreeNode(item))
+			}
+			node.AddChild(child)
+		}
+		client.app.Unlock()
+
+		client.app.Draw()
+	}
+}
+
+func SetSeats(name string, seats []libs.Seat) {
+	items := make([]string, 0, len(seats))
+	for _, seat := range seats {
+		items = append(items, fmt.Sprintf("[%d] %s", seat.Idx, seat.Addr))
+	}
+
+	setPluginChild(name, "Seats", items)
+}
+
+func SetPriority(name string, priority [][]int) {
+	items := make([]string, 0, len(priority))
+	for lvl, seats := range priority {
+		items = append(items, fmt.Sprintf("L%d: %v", lvl+1, seats))
+	}
+
+	setPluginChild(name, "Priority", items)
+}
+
+// expandPlugin 展开插件节点时查询插件的席位及优先级
+func expandPlugin(node *tview.TreeNode, name string) {
+	if node.IsExpanded() {
+		node.Collapse()
+		return
+	}
+	node.Expand()
+
+	client := instance.Load()
+	if client == nil {
+		return
+	}
+
+	for _, cmdName := range []string{"seats", "priority"} {
+		if !client.client.GetServerHello().Supports(cmdName) {
+			continue
+		}
+
+		callCommand(client.client, &ctl.Command{
+			Name:   cmdName,
+			KwArgs: map[string]string{"plugin": name},
+		})
+	}
+}
+
 func init() {
 	ctlSvrView.SetDirection(
 		tview.FlexRow,
@@ -112,17 +265,16 @@ func init() {
 	infoNodes.SetRoot(
 		root,
 	).SetSelectedFunc(func(node *tview.TreeNode) {
-		ref := node.GetReference()
-		if ref == nil {
+		if node == pluginNode {
+			node.SetExpanded(!node.IsExpanded())
 			return
 		}
 
-		switch node.GetText() {
-		case "Plugins":
-			// TODO expand child
+		if p, ok := node.GetReference().(*libs.PluginContainer); ok {
+			expandPlugin(node, p.Name())
 		}
 	}).SetTitle(
-		" Info ",
+		" Info [F3] ",
 	).SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
 		switch event.Key() {
 		case tcell.KeyUp:
@@ -26,7 +26,14 @@ func init() {
 		),
 		0, 5, false,
 	).AddItem(
-		logView, 0, 5, false,
+		tview.NewFlex().AddItem(
+			logView, 0, 1, false,
+		).AddItem(
+			serverLogView, 0, 1, false,
+		).AddItem(
+			proposalView, 0, 1, false,
+		),
+		0, 5, false,
 	).AddItem(
 		commandView, 1, 0, true,
 	).SetTitle(
@@ -0,0 +1,144 @@
+package tui
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+	"github.com/frozenpine/latency4go/ctl"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+var (
+	proposalView = tview.NewList()
+
+	// pendingProposals 待审批的提案，按 ID 升序
+	pendingProposals []*latency4go.Proposal
+	proposalLock     sync.Mutex
+)
+
+func init() {
+	proposalView.SetSelectedFocusOnly(
+		true,
+	).SetBorder(
+		true,
+	).SetTitle(
+		" Proposals [F2] ",
+	).SetTitleAlign(
+		tview.AlignCenter,
+	).SetBorderPadding(
+		0, 0, 1, 1,
+	)
+
+	proposalView.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'a':
+			decideProposal("approve")
+		case 'r':
+			decideProposal("reject")
+		default:
+			return event
+		}
+
+		return nil
+	})
+}
+
+// diffText 提案相对当前顺序的排名变化，+ 为新增前置，- 为移除前置
+func diffText(changes []latency4go.RankChange) string {
+	items := make([]string, 0, len(changes))
+
+	for _, c := range changes {
+		switch {
+		case c.From == 0:
+			items = append(items, fmt.Sprintf("+%s→%d", c.Addr, c.To))
+		case c.To == 0:
+			items = append(items, fmt.Sprintf("-%s", c.Addr))
+		default:
+			items = append(items, fmt.Sprintf("%s %d→%d", c.Addr, c.From, c.To))
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func drawProposals() {
+	if client := instance.Load(); client != nil {
+		proposalLock.Lock()
+		proposals := slices.Clone(pendingProposals)
+		proposalLock.Unlock()
+
+		client.app.Lock()
+		current := proposalView.GetCurrentItem()
+		proposalView.Clear()
+		for _, p := range proposals {
+			proposalView.AddItem(
+				fmt.Sprintf(
+					"#%d %s expire %s", p.ID, p.Reporter,
+					p.Expire.Local().Format(time.TimeOnly),
+				),
+				diffText(p.Diff), 0, nil,
+			)
+		}
+		proposalView.SetCurrentItem(min(current, max(len(proposals)-1, 0)))
+		proposalView.SetTitle(fmt.Sprintf(" Proposals [%d] [F2] ", len(proposals)))
+		client.app.Unlock()
+
+		client.app.Draw()
+	}
+}
+
+// SetProposals 替换全部待审批提案
+func SetProposals(proposals []*latency4go.Proposal) {
+	proposalLock.Lock()
+	pendingProposals = slices.Clone(proposals)
+	slices.SortFunc(pendingProposals, func(a, b *latency4go.Proposal) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+	proposalLock.Unlock()
+
+	drawProposals()
+}
+
+// UpdateProposal 按提案状态新增或移除待审批提案
+func UpdateProposal(p *latency4go.Proposal) {
+	proposalLock.Lock()
+	pendingProposals = slices.DeleteFunc(pendingProposals, func(v *latency4go.Proposal) bool {
+		return v.ID == p.ID
+	})
+	if p.Status == latency4go.ProposalPending {
+		pendingProposals = append(pendingProposals, p)
+	}
+	proposalLock.Unlock()
+
+	drawProposals()
+}
+
+// decideProposal 批准或拒绝选中的提案，在 UI 协程中调用
+func decideProposal(cmdName string) {
+	client := instance.Load()
+	if client == nil {
+		return
+	}
+
+	idx := proposalView.GetCurrentItem()
+
+	proposalLock.Lock()
+	if idx < 0 || idx >= len(pendingProposals) {
+		proposalLock.Unlock()
+		return
+	}
+	p := pendingProposals[idx]
+	proposalLock.Unlock()
+
+	callCommand(client.client, &ctl.Command{
+		Name:   cmdName,
+		KwArgs: map[string]string{"id": strconv.FormatUint(p.ID, 10)},
+	})
+}
@@ -2,9 +2,12 @@ package tui
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -47,101 +50,357 @@ func handleState(state *latency4go.State) error {
 }
 
 func handleResultState(r *ctl.Result) error {
-	stateV, ok := r.Values[ctl.VKeyState].(json.RawMessage)
-	if !ok {
+	state, exist, err := ctl.GetResultValue[*latency4go.State](r, ctl.VKeyState)
+	if err != nil {
+		return err
+	} else if !exist {
 		slog.Warn("no state in info result")
-	} else {
-		var state latency4go.State
-		if err := json.Unmarshal(stateV, &state); err != nil {
-			return err
-		} else if err := handleState(&state); err != nil {
-			return err
-		}
+		return nil
 	}
 
-	return nil
+	return handleState(state)
 }
 
-func handleResultInfo(r *ctl.Result) error {
-	stateV, ok := r.Values[ctl.VKeyState].(json.RawMessage)
-	if !ok {
-		if r.CmdName != "start" {
-			slog.Warn("no state in info result")
-		}
-	} else {
-		var state latency4go.State
-		if err := json.Unmarshal(stateV, &state); err != nil {
-			return err
-		} else if err := handleState(&state); err != nil {
-			return err
-		}
+func handleResultSeats(r *ctl.Result) error {
+	name, _, err := ctl.GetResultValue[string](r, ctl.VKeyPluginName)
+	if err != nil {
+		return err
 	}
 
-	interV, ok := r.Values[ctl.VKeyInterval].(json.RawMessage)
-	if !ok {
-		slog.Warn("no interval in info result")
-	} else {
-		var interval time.Duration
-		if err := json.Unmarshal(interV, &interval); err != nil {
-			return err
-		}
+	seats, _, err := ctl.GetResultValue[[]libs.Seat](r, ctl.VKeySeat)
+	if err != nil {
+		return err
+	}
+
+	SetSeats(name, seats)
+
+	return nil
+}
 
-		SetInterval(interval)
+func handleResultPriority(r *ctl.Result) error {
+	name, _, err := ctl.GetResultValue[string](r, ctl.VKeyPluginName)
+	if err != nil {
+		return err
 	}
 
-	hdlV, ok := r.Values[ctl.VKeyHandler].(json.RawMessage)
-	if !ok {
-		slog.Warn("no handlers in info result")
-	} else {
-		var handlers = []string{}
-		if err := json.Unmarshal(hdlV, &handlers); err != nil {
-			return err
-		}
+	priority, _, err := ctl.GetResultValue[[][]int](r, ctl.VKeyPriority)
+	if err != nil {
+		return err
+	}
+
+	SetPriority(name, priority)
+
+	return nil
+}
 
-		SetSummary(handlers)
+func handleResultInfo(r *ctl.Result) error {
+	info, err := ctl.NewCtlInfo(r)
+	if err != nil {
+		return err
 	}
 
-	pluginV, ok := r.Values[ctl.VKeyPlugin].(json.RawMessage)
-	if !ok {
-		slog.Warn("no plugins in info result")
-	} else {
-		var plugins = []*libs.PluginContainer{}
-		if err := json.Unmarshal(pluginV, &plugins); err != nil {
+	if info.State != nil {
+		if err := handleState(info.State); err != nil {
 			return err
 		}
+	} else if r.CmdName != "start" {
+		slog.Warn("no state in info result")
+	}
+
+	SetInterval(info.Interval)
+	SetSummary(info.Handlers)
+	SetPlugins(info.Plugins)
+	SetPins(info.Pins)
 
-		SetPlugins(plugins)
+	return nil
+}
+
+func handleResultPins(r *ctl.Result) error {
+	pins, exist, err := ctl.GetResultValue[map[string]time.Time](r, ctl.VKeyPin)
+	if err != nil || !exist {
+		return err
 	}
 
+	SetPins(pins)
+
 	return nil
 }
 
 func handleResultPeriod(r *ctl.Result) error {
-	newV, ok := r.Values[ctl.VKeyInterval].(json.RawMessage)
-	if !ok {
+	interv, exist, err := ctl.GetResultValue[time.Duration](r, ctl.VKeyInterval)
+	if err != nil {
+		return err
+	} else if !exist {
 		slog.Warn("no interval in period result")
-	} else {
-		var interv time.Duration
-		if err := json.Unmarshal(newV, &interv); err != nil {
-			return err
-		}
-
-		SetInterval(interv)
+		return nil
 	}
 
+	SetInterval(interv)
+
 	return nil
 }
 
 func handleResultConfig(r *ctl.Result) error {
-	cfgV, ok := r.Values[ctl.VKeyConfig].(json.RawMessage)
-	if !ok {
+	cfg, exist, err := ctl.GetResultValue[latency4go.QueryConfig](r, ctl.VKeyConfig)
+	if err != nil {
+		return err
+	} else if !exist {
 		slog.Warn("no config in result")
 	} else if state := lastState.Load(); state != nil {
-		if err := json.Unmarshal(cfgV, &state.Config); err != nil {
+		state.Config = cfg
+
+		SetConfig()
+	}
+
+	return nil
+}
+
+func handleResultAudit(r *ctl.Result) error {
+	entries, exist, err := ctl.GetResultValue[[]*ctl.AuditEntry](r, ctl.VKeyAudit)
+	if err != nil {
+		return err
+	} else if !exist {
+		slog.Warn("no entries in audit result")
+		return nil
+	}
+
+	buff := strings.Builder{}
+	buff.WriteString(
+		"═══════════════════════════ Audit ═══════════════════════════\n",
+	)
+	for _, entry := range entries {
+		identity := entry.Identity
+		if identity == "" {
+			identity = entry.Remote
+		}
+
+		fmt.Fprintf(
+			&buff, " %s %s[%s] %s %v => %d %s",
+			entry.Timestamp.Format(time.DateTime), identity, entry.Role,
+			entry.Command, entry.KwArgs, entry.Rtn, entry.Message,
+		)
+		if entry.Before != nil && entry.After != nil {
+			fmt.Fprintf(
+				&buff, "\n     config: %s\n          => %s",
+				entry.Before.String(), entry.After.String(),
+			)
+		}
+		buff.WriteByte('\n')
+	}
+
+	_, err = logView.Write([]byte(buff.String()))
+	return err
+}
+
+func handleResultProposals(r *ctl.Result) error {
+	proposals, exist, err := ctl.GetResultValue[[]*latency4go.Proposal](
+		r, ctl.VKeyProposal,
+	)
+	if err != nil || !exist {
+		return err
+	}
+
+	if r.CmdName == "proposals" {
+		SetProposals(proposals)
+		return nil
+	}
+
+	for _, p := range proposals {
+		UpdateProposal(p)
+	}
+
+	return nil
+}
+
+func handleResultHistory(r *ctl.Result) error {
+	history, exist, err := ctl.GetResultValue[map[string][]*latency4go.State](
+		r, ctl.VKeyHistory,
+	)
+	if err != nil {
+		return err
+	} else if !exist {
+		slog.Warn("no history in history result")
+		return nil
+	}
+
+	plugins := slices.Sorted(maps.Keys(history))
+
+	buff := strings.Builder{}
+	buff.WriteString(
+		"══════════════════════════ History ══════════════════════════\n",
+	)
+	for _, name := range plugins {
+		states := history[name]
+
+		fmt.Fprintf(&buff, " %s:\n", name)
+		// 序号即 rollback 的 steps，0 为最近一次上报
+		for idx := len(states) - 1; idx >= 0; idx-- {
+			fmt.Fprintf(
+				&buff, "   %2d %s %v\n", len(states)-1-idx,
+				states[idx].Timestamp.Format(time.DateTime), states[idx].AddrList,
+			)
+		}
+	}
+
+	_, err = logView.Write([]byte(buff.String()))
+	return err
+}
+
+func handleResultSessions(r *ctl.Result) error {
+	sessions, exist, err := ctl.GetResultValue[[]ctl.SessionInfo](r, ctl.VKeySession)
+	if err != nil {
+		return err
+	} else if !exist {
+		slog.Warn("no sessions in sessions result")
+		return nil
+	}
+
+	buff := strings.Builder{}
+	buff.WriteString(
+		"══════════════════════════ Sessions ═════════════════════════\n",
+	)
+	for _, sess := range sessions {
+		fmt.Fprintf(
+			&buff, " %s %s %s[%s]",
+			sess.Handler, sess.Remote, sess.Identity, sess.Role,
+		)
+		if !sess.Connected.IsZero() {
+			fmt.Fprintf(&buff, " since %s", sess.Connected.Format(time.DateTime))
+		}
+		if sess.LastCommand != "" {
+			fmt.Fprintf(
+				&buff, " last %s@%s",
+				sess.LastCommand, sess.LastActive.Format(time.TimeOnly),
+			)
+		}
+		if len(sess.Subscriptions) > 0 {
+			fmt.Fprintf(
+				&buff, " subs %v",
+				slices.Sorted(maps.Keys(sess.Subscriptions)),
+			)
+		}
+		if sess.Queue != nil {
+			fmt.Fprintf(
+				&buff, " queue %d/%d dropped %d",
+				sess.Queue.Depth, sess.Queue.Size, sess.Queue.Dropped,
+			)
+		}
+		buff.WriteByte('\n')
+	}
+
+	_, err = logView.Write([]byte(buff.String()))
+	return err
+}
+
+// handleResult 按命令名称更新界面，失败的结果仅记录日志
+func handleResult(r *ctl.Result) error {
+	if ctl.LogResult(r) != nil {
+		return nil
+	}
+
+	switch r.CmdName {
+	case "info":
+		return handleResultInfo(r)
+	case "state":
+		return handleResultState(r)
+	case "period":
+		return handleResultPeriod(r)
+	case "config":
+		return handleResultConfig(r)
+	case "start":
+		return handleResultInfo(r)
+	case "audit":
+		return handleResultAudit(r)
+	case "sessions":
+		return handleResultSessions(r)
+	case "schema":
+		return handleResultSchema(r)
+	case "push", "unpin", "rollback":
+		return handleResultPins(r)
+	case "proposals", "approve", "reject":
+		return handleResultProposals(r)
+	case "history":
+		return handleResultHistory(r)
+	case "seats":
+		return handleResultSeats(r)
+	case "priority":
+		return handleResultPriority(r)
+	default:
+		return nil
+	}
+}
+
+func handleProgress(p *ctl.Progress) error {
+	slog.Info(
+		"command in progress",
+		slog.Uint64("seq", p.MsgID),
+		slog.String("cmd", p.CmdName),
+		slog.String("stage", p.Stage),
+		slog.String("message", p.Message),
+		slog.Duration("elapsed", p.Elapsed.Truncate(time.Millisecond)),
+	)
+
+	return nil
+}
+
+func handleEvent(e *ctl.Event) error {
+	switch e.Topic {
+	case ctl.TopicPlugin:
+		event, err := ctl.GetEventData[ctl.PluginEvent](e)
+		if err != nil {
 			return err
 		}
 
-		SetConfig()
+		slog.Info(
+			"ctl server plugin event",
+			slog.String("plugin", event.Name),
+			slog.String("action", event.Action),
+			slog.String("message", event.Message),
+		)
+
+		// 刷新插件列表
+		if client := instance.Load(); client != nil {
+			callCommand(client.client, &ctl.Command{Name: "info"})
+		}
+	case ctl.TopicProposal:
+		p, err := ctl.GetEventData[latency4go.Proposal](e)
+		if err != nil {
+			return err
+		}
+
+		level := slog.LevelInfo
+		if p.Status == latency4go.ProposalPending {
+			level = slog.LevelWarn
+		}
+
+		slog.Log(
+			context.Background(), level,
+			"ctl server priority proposal",
+			slog.Uint64("id", p.ID),
+			slog.String("reporter", p.Reporter),
+			slog.String("status", string(p.Status)),
+			slog.String("diff", diffText(p.Diff)),
+		)
+
+		UpdateProposal(p)
+	case ctl.TopicAlert:
+		alert, err := ctl.GetEventData[ctl.Alert](e)
+		if err != nil {
+			return err
+		}
+
+		slog.Log(
+			context.Background(), alert.Level,
+			"ctl server alert",
+			slog.Time("timestamp", e.Timestamp),
+			slog.String("source", alert.Source),
+			slog.String("message", alert.Message),
+		)
+	default:
+		slog.Warn(
+			"unsupported event topic",
+			slog.String("topic", string(e.Topic)),
+		)
 	}
 
 	return nil
@@ -167,6 +426,15 @@ func StartTui(
 		case tcell.KeyCtrlC:
 			commandView.SetText("")
 			return nil
+		case tcell.KeyF2:
+			app.SetFocus(proposalView)
+			return nil
+		case tcell.KeyF3:
+			app.SetFocus(infoNodes)
+			return nil
+		case tcell.KeyEsc:
+			app.SetFocus(commandView)
+			return nil
 		}
 		return event
 	})
@@ -180,31 +448,16 @@ func StartTui(
 	instance.Store(tuiClient)
 	go app.Run()
 
+	client.WatchConnState("tui", SetConnState)
+	go watchRTT(ctx, time.Second)
 	client.Init(ctx, "ctl client", client.Start)
 
+	// 消息循环仅处理 SDK 自行发送的命令（如连接及重连后的 info）及超时后到达的结果，
+	// TUI 发送的命令结果由 Call 按 msgID 返回
 	if err := client.MessageLoop(
 		"tui loop", nil,
 		handleState,
-		func(r *ctl.Result) error {
-			if ctl.LogResult(r) != nil {
-				return nil
-			}
-
-			switch r.CmdName {
-			case "info":
-				return handleResultInfo(r)
-			case "state":
-				return handleResultState(r)
-			case "period":
-				return handleResultPeriod(r)
-			case "config":
-				return handleResultConfig(r)
-			case "start":
-				return handleResultInfo(r)
-			default:
-				return nil
-			}
-		},
+		handleResult,
 		func() error {
 			slog.Error("ctl client message loop ended, quit in 5s")
 			<-time.After(time.Second * 5)
@@ -215,5 +468,59 @@ func StartTui(
 		return err
 	}
 
+	if err := client.EventLoop("tui events", handleEvent); err != nil {
+		return err
+	}
+
+	if err := client.LogLoop("tui server logs", handleServerLog); err != nil {
+		return err
+	}
+
+	if err := client.ProgressLoop("tui progress", handleProgress); err != nil {
+		return err
+	}
+
+	// 获取服务端命令定义，用于帮助、参数校验及补全，旧版服务端使用本地内置定义
+	if hello := client.GetServerHello(); hello != nil && hello.Supports("schema") {
+		callCommand(client, &ctl.Command{Name: "schema"})
+	}
+
+	if hello := client.GetServerHello(); hello != nil && hello.Supports("proposals") {
+		callCommand(client, &ctl.Command{Name: "proposals"})
+	}
+
+	for _, topic := range supportedTopics(client.GetServerHello()) {
+		callCommand(client, &ctl.Command{
+			Name:   "subscribe",
+			KwArgs: map[string]string{"topic": string(topic)},
+		})
+	}
+
 	return nil
 }
+
+// supportedTopics 按服务端握手声明的能力选择启动时订阅的主题，旧版服务端不订阅
+func supportedTopics(hello *ctl.Hello) []ctl.Topic {
+	if hello == nil {
+		return []ctl.Topic{ctl.TopicPlugin, ctl.TopicAlert, ctl.TopicLog}
+	}
+
+	if !hello.Supports("subscribe") || !hello.HasCapability(ctl.CapTopic) {
+		slog.Warn(
+			"ctl server not support topic subscription",
+			slog.Int("version", hello.Version),
+			slog.String("build", hello.Build),
+		)
+		return nil
+	}
+
+	topics := []ctl.Topic{ctl.TopicPlugin, ctl.TopicAlert}
+	if hello.HasCapability(ctl.CapLog) {
+		topics = append(topics, ctl.TopicLog)
+	}
+	if hello.Supports("approve") {
+		topics = append(topics, ctl.TopicProposal)
+	}
+
+	return topics
+}
@@ -0,0 +1,183 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/frozenpine/latency4go/ctl"
+	"github.com/spf13/pflag"
+)
+
+// commandSchema 服务端命令定义，schema 命令返回前使用本地内置命令定义
+var commandSchema atomic.Pointer[[]*ctl.CommandSpec]
+
+// localCommands TUI 本地处理的命令
+var localCommands = []string{"logs", "help", "top", "exit"}
+
+func getCommandSpecs() []*ctl.CommandSpec {
+	if specs := commandSchema.Load(); specs != nil {
+		return *specs
+	}
+
+	specs := []*ctl.CommandSpec{}
+
+	// 旧版服务端仅支持握手声明的命令
+	var hello *ctl.Hello
+	if client := instance.Load(); client != nil {
+		hello = client.client.GetServerHello()
+	}
+
+	for _, spec := range ctl.CommandSpecs() {
+		if !spec.Custom && hello.Supports(spec.Name) {
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs
+}
+
+func getCommandSpec(name string) *ctl.CommandSpec {
+	specs := getCommandSpecs()
+
+	if idx := slices.IndexFunc(specs, func(spec *ctl.CommandSpec) bool {
+		return spec.Name == name
+	}); idx >= 0 {
+		return specs[idx]
+	}
+
+	return nil
+}
+
+func handleResultSchema(r *ctl.Result) error {
+	specs, exist, err := ctl.GetResultValue[[]*ctl.CommandSpec](r, ctl.VKeySchema)
+	if err != nil {
+		return err
+	} else if !exist || len(specs) == 0 {
+		return nil
+	}
+
+	// 指定命令名称的查询仅返回单个命令，按名称合并至已有定义
+	for _, spec := range getCommandSpecs() {
+		if !slices.ContainsFunc(specs, func(s *ctl.CommandSpec) bool {
+			return s.Name == spec.Name
+		}) {
+			specs = append(specs, spec)
+		}
+	}
+
+	slices.SortFunc(specs, func(a, b *ctl.CommandSpec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	commandSchema.Store(&specs)
+
+	return nil
+}
+
+// schemaFlags 按命令定义生成远程命令的参数解析，位置参数亦可以 --name 形式指定，
+// 参数值原样传递由服务端校验
+func schemaFlags(spec *ctl.CommandSpec) *pflag.FlagSet {
+	flags := pflag.NewFlagSet(spec.Name, pflag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	for _, arg := range spec.Args {
+		flags.String(arg.Name, "", arg.Help)
+	}
+
+	return flags
+}
+
+func commandHelpText() string {
+	var buff strings.Builder
+
+	buff.WriteString(commandHelpHeader)
+
+	for _, spec := range getCommandSpecs() {
+		fmt.Fprintf(&buff, "%11s: %s\n", spec.Name, spec.Help)
+	}
+
+	buff.WriteString(commandHelpLocal)
+
+	return buff.String()
+}
+
+func commandDetailText(spec *ctl.CommandSpec) string {
+	var buff strings.Builder
+
+	buff.WriteString(detailSeparator)
+	fmt.Fprintf(&buff, " Command > %s ↵\n", spec.Usage())
+	fmt.Fprintf(&buff, "   %s, role: %s\n", spec.Help, spec.Role)
+
+	for _, arg := range spec.Args {
+		name := arg.Name
+		if !arg.Positional {
+			name = "--" + name
+		}
+
+		fmt.Fprintf(&buff, "   %12s %-8s %s", name, arg.Type, arg.Help)
+		if len(arg.Enum) > 0 {
+			fmt.Fprintf(&buff, " [%s]", strings.Join(arg.Enum, "|"))
+		}
+		if arg.Required {
+			buff.WriteString(" (required)")
+		}
+		buff.WriteByte('\n')
+	}
+
+	buff.WriteString(detailSeparator)
+
+	return buff.String()
+}
+
+// completeCommand 补全命令名称或参数名称，返回补全后的文本及全部候选
+func completeCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || strings.HasSuffix(text, " ") {
+		return text, nil
+	}
+
+	last := fields[len(fields)-1]
+	prefix := text[:len(text)-len(last)]
+	candidates := []string{}
+
+	if len(fields) == 1 {
+		for _, spec := range getCommandSpecs() {
+			if strings.HasPrefix(spec.Name, last) {
+				candidates = append(candidates, spec.Name)
+			}
+		}
+
+		for _, name := range localCommands {
+			if strings.HasPrefix(name, last) {
+				candidates = append(candidates, name)
+			}
+		}
+	} else if spec := getCommandSpec(fields[0]); spec != nil &&
+		strings.HasPrefix(last, "--") {
+		for _, arg := range spec.Args {
+			if flag := "--" + arg.Name; !arg.Positional &&
+				strings.HasPrefix(flag, last) {
+				candidates = append(candidates, flag)
+			}
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return text, nil
+	case 1:
+		return prefix + candidates[0] + " ", candidates
+	default:
+		common := candidates[0]
+		for _, c := range candidates[1:] {
+			for !strings.HasPrefix(c, common) {
+				common = common[:len(common)-1]
+			}
+		}
+
+		return prefix + common, candidates
+	}
+}
@@ -0,0 +1,111 @@
+package tui
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/frozenpine/latency4go/ctl"
+	"github.com/rivo/tview"
+	"github.com/valyala/bytebufferpool"
+)
+
+var (
+	serverLogView = tview.NewTextView()
+
+	// serverLogKeyword 服务端日志的本地关键字过滤
+	serverLogKeyword atomic.Pointer[string]
+)
+
+func init() {
+	serverLogView.SetDynamicColors(
+		true,
+	).SetWordWrap(
+		true,
+	).SetMaxLines(
+		200,
+	).SetChangedFunc(func() {
+		if client := instance.Load(); client != nil {
+			serverLogView.Lock()
+			serverLogView.ScrollToEnd()
+			serverLogView.Unlock()
+
+			client.app.Draw()
+		}
+	}).SetBorder(
+		true,
+	).SetTitleAlign(
+		tview.AlignCenter,
+	)
+
+	setServerLogTitle(slog.LevelInfo, "")
+}
+
+func setServerLogTitle(level slog.Level, keyword string) {
+	title := fmt.Sprintf(" Server Log [orange]%s[white] ", level)
+	if keyword != "" {
+		title = fmt.Sprintf(
+			" Server Log [orange]%s[white] grep [orange]%s[white] ",
+			level, tview.Escape(keyword),
+		)
+	}
+
+	serverLogView.SetTitle(title)
+}
+
+// SetServerLogFilter 修改服务端日志过滤条件，级别由服务端过滤，关键字在本地过滤
+func SetServerLogFilter(level slog.Level, keyword string) {
+	if keyword == "" {
+		serverLogKeyword.Store(nil)
+	} else {
+		serverLogKeyword.Store(&keyword)
+	}
+
+	if client := instance.Load(); client != nil {
+		client.app.Lock()
+		setServerLogTitle(level, keyword)
+		client.app.Unlock()
+
+		client.app.Draw()
+	}
+}
+
+func handleServerLog(record *ctl.LogRecord) error {
+	buff := bytebufferpool.Get()
+	defer bytebufferpool.Put(buff)
+
+	var color string
+	switch {
+	case record.Level >= slog.LevelError:
+		color = "[red]"
+	case record.Level >= slog.LevelWarn:
+		color = "[orange]"
+	case record.Level >= slog.LevelInfo:
+		color = "[green]"
+	default:
+		color = "[gray]"
+	}
+
+	fmt.Fprintf(
+		buff, "[gray]%s[white] %s%s[white] %s",
+		record.Time.Format(time.TimeOnly), color, record.Level,
+		tview.Escape(record.Message),
+	)
+	for _, key := range slices.Sorted(maps.Keys(record.Attrs)) {
+		fmt.Fprintf(buff, " %s=%s", key, tview.Escape(record.Attrs[key]))
+	}
+
+	if keyword := serverLogKeyword.Load(); keyword != nil &&
+		!strings.Contains(buff.String(), *keyword) {
+		return nil
+	}
+
+	buff.WriteByte('\n')
+
+	_, err := serverLogView.Write(buff.Bytes())
+	return err
+}
@@ -25,8 +25,12 @@ var (
 	ErrParseAggResult     = errors.New("parse aggregation failed")
 	ErrInvalidQueryCfg    = errors.New("invalid query config")
 	ErrInvalidReporter    = errors.New("invalid reporter")
+	ErrNoReportHistory    = errors.New("no report history")
 )
 
+// DefaultHistorySize 每个 reporter 默认保留的已上报状态数
+const DefaultHistorySize = 10
+
 type CTX_KEY string
 
 const CTX_VERBOSE_KEY CTX_KEY = "verbose"
@@ -68,6 +72,50 @@ type LatencyClient struct {
 	lastReport atomic.Pointer[LatencyReport]
 	reporterWg sync.WaitGroup
 	reporters  sync.Map
+	reportLock sync.Mutex
+	pins       sync.Map
+
+	historySize atomic.Int64
+	history     sync.Map
+
+	approvals    sync.Map
+	proposalFn   atomic.Pointer[ProposalNotifier]
+	proposalSeq  atomic.Uint64
+	proposalLock sync.Mutex
+	proposals    map[uint64]*Proposal
+}
+
+// reportHistory reporter 已成功上报的状态，按上报时间升序
+type reportHistory struct {
+	lock   sync.Mutex
+	states []*State
+}
+
+func (h *reportHistory) append(state *State, size int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.states = append(h.states, state)
+	if over := len(h.states) - size; over > 0 {
+		h.states = slices.Delete(h.states, 0, over)
+	}
+}
+
+// rewind 回退至已上报的 state，丢弃其后的记录
+func (h *reportHistory) rewind(state *State) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if idx := slices.Index(h.states, state); idx >= 0 {
+		h.states = slices.Delete(h.states, idx+1, len(h.states))
+	}
+}
+
+func (h *reportHistory) list() []*State {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return slices.Clone(h.states)
 }
 
 func (c *LatencyClient) Init(
@@ -176,12 +224,18 @@ func (c *LatencyClient) GetVersion() (string, error) {
 	return "", ErrNotInitialized
 }
 
-func (c *LatencyClient) queryLatency(cfg *QueryConfig) ([]*ExFrontLatency, error) {
+func (c *LatencyClient) queryLatency(
+	ctx context.Context, cfg *QueryConfig,
+) ([]*ExFrontLatency, error) {
 	qry, agg := cfg.makeQuery()
 
-	qryCtx, qryCancel := context.WithCancel(c.runCtx)
+	qryCtx, qryCancel := context.WithCancel(ctx)
 	defer qryCancel()
 
+	// 调用方的 ctx 之外，查询同时随客户端停止而取消
+	stop := context.AfterFunc(c.runCtx, qryCancel)
+	defer stop()
+
 	rsp, err := c.client.Load().Search(
 		ELASTIC_DOCUMENTS,
 	).Size(
@@ -404,7 +458,7 @@ func (c *LatencyClient) runQuerier(
 
 				currCfg := *c.cfg.Load()
 				ts := time.Now()
-				latency, err := c.queryLatency(&currCfg)
+				latency, err := c.queryLatency(c.runCtx, &currCfg)
 
 				if err != nil {
 					slog.Error(
@@ -509,19 +563,21 @@ func (c *LatencyClient) runReporter() {
 				return true
 			}
 
-			slog.Info(
-				"sending latency state to reporter",
-				slog.String("reporter", name),
-			)
-
-			if err := reportFn(state); err != nil {
-				slog.Error(
-					"send latency state to reporter failed",
-					slog.Any("error", err),
+			if until, pinned := c.pinnedUntil(name); pinned {
+				slog.Warn(
+					"reporter pinned, skip latency state",
 					slog.String("reporter", name),
+					slog.Time("until", until),
 				)
+				return true
 			}
 
+			if c.propose(name, state) {
+				return true
+			}
+
+			c.report(name, reportFn, state)
+
 			return true
 		})
 	}
@@ -529,6 +585,223 @@ func (c *LatencyClient) runReporter() {
 	slog.Info("latency notify channel closed")
 }
 
+func (c *LatencyClient) report(name string, reportFn Reporter, state *State) error {
+	return c.deliver(name, reportFn, state, false)
+}
+
+// deliver 经 reporter 上报 state，成功后记录上报历史，
+// rewind 为 true 时 state 为历史中的状态，回退历史而非追加
+func (c *LatencyClient) deliver(
+	name string, reportFn Reporter, state *State, rewind bool,
+) error {
+	// 周期上报与手动推送共用 reporter，串行调用
+	c.reportLock.Lock()
+	defer c.reportLock.Unlock()
+
+	slog.Info(
+		"sending latency state to reporter",
+		slog.String("reporter", name),
+	)
+
+	if err := reportFn(state); err != nil {
+		slog.Error(
+			"send latency state to reporter failed",
+			slog.Any("error", err),
+			slog.String("reporter", name),
+		)
+		return err
+	}
+
+	v, _ := c.history.LoadOrStore(name, &reportHistory{})
+	if rewind {
+		v.(*reportHistory).rewind(state)
+	} else {
+		v.(*reportHistory).append(state, c.getHistorySize())
+	}
+
+	return nil
+}
+
+func (c *LatencyClient) getHistorySize() int {
+	if size := c.historySize.Load(); size > 0 {
+		return int(size)
+	}
+
+	return DefaultHistorySize
+}
+
+// SetHistorySize 设置每个 reporter 保留的已上报状态数，size 不大于 0 时使用默认值
+func (c *LatencyClient) SetHistorySize(size int) {
+	c.historySize.Store(int64(size))
+}
+
+// GetHistory reporter 最近已成功上报的状态，按上报时间升序
+func (c *LatencyClient) GetHistory(name string) []*State {
+	if v, exist := c.history.Load(name); exist {
+		return v.(*reportHistory).list()
+	}
+
+	return nil
+}
+
+// Rollback 将 reporter steps 次上报前的状态重新上报，并丢弃其后的上报历史，
+// 连续回退逐次回到更早的状态；hold 大于 0 时在此期间锁定 reporter，暂停周期上报。
+// 回退为人工干预，审批模式下同样直接上报，并以 superseded 结束 reporter 的待审批提案
+func (c *LatencyClient) Rollback(
+	name string, steps int, hold time.Duration,
+) (*State, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("%w: invalid rollback steps %d", ErrNoReportHistory, steps)
+	}
+
+	states := c.GetHistory(name)
+	idx := len(states) - 1 - steps
+	if idx < 0 {
+		return nil, fmt.Errorf(
+			"%w: %s has %d reported states", ErrNoReportHistory, name, len(states),
+		)
+	}
+
+	v, exist := c.reporters.Load(name)
+	if !exist {
+		return nil, fmt.Errorf(
+			"%w: %s reporter not exists", ErrInvalidReporter, name,
+		)
+	}
+
+	var until time.Time
+	if hold > 0 {
+		until = time.Now().Add(hold)
+	}
+	// 先锁定再上报，避免上报期间的周期上报覆盖回退的状态
+	restore := c.pin(name, until)
+
+	if err := c.deliver(name, v.(Reporter), states[idx], true); err != nil {
+		restore()
+		return states[idx], err
+	}
+
+	c.closeProposals(name, ProposalSuperseded)
+
+	return states[idx], nil
+}
+
+// pin 锁定 reporter 至 until，until 不晚于当前时间时解除锁定，
+// 返回恢复原有锁定的函数，供上报失败时撤销本次锁定，锁定已被其他操作变更时不做恢复
+func (c *LatencyClient) pin(name string, until time.Time) (restore func()) {
+	prev, pinned := c.pins.Load(name)
+
+	if !time.Now().Before(until) {
+		c.pins.Delete(name)
+
+		return func() {
+			if pinned {
+				c.pins.LoadOrStore(name, prev)
+			}
+		}
+	}
+
+	c.pins.Store(name, until)
+
+	return func() {
+		if pinned {
+			c.pins.CompareAndSwap(name, until, prev)
+		} else {
+			c.pins.CompareAndDelete(name, until)
+		}
+	}
+}
+
+// pinnedUntil 返回 reporter 的锁定截止时间，已过期的锁定自动清除
+func (c *LatencyClient) pinnedUntil(name string) (time.Time, bool) {
+	v, exist := c.pins.Load(name)
+	if !exist {
+		return time.Time{}, false
+	}
+
+	until := v.(time.Time)
+	if time.Now().Before(until) {
+		return until, true
+	}
+
+	c.pins.CompareAndDelete(name, v)
+	slog.Info(
+		"reporter pin expired",
+		slog.String("reporter", name),
+	)
+
+	return time.Time{}, false
+}
+
+// Push 跳过延迟查询，将 state 经指定的 reporter 立即上报，
+// until 晚于当前时间时锁定 reporter，截止前的周期上报不再覆盖已推送的状态，
+// 否则解除 reporter 已有的锁定，上报失败的 reporter 保持原有锁定。推送为人工干预，审批模式下同样直接上报，
+// 并以 superseded 结束 reporter 的待审批提案
+func (c *LatencyClient) Push(state *State, until time.Time, names ...string) error {
+	if state == nil || len(state.AddrList) == 0 {
+		return errors.New("empty push state")
+	}
+
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no reporter specified", ErrInvalidReporter)
+	}
+
+	reporters := make([]Reporter, 0, len(names))
+	for _, name := range names {
+		v, exist := c.reporters.Load(name)
+		if !exist {
+			return fmt.Errorf(
+				"%w: %s reporter not exists", ErrInvalidReporter, name,
+			)
+		}
+
+		reporters = append(reporters, v.(Reporter))
+	}
+
+	errs := []error{}
+	for idx, name := range names {
+		// 先锁定再上报，避免上报期间的周期上报覆盖推送的状态，上报失败时撤销
+		restore := c.pin(name, until)
+
+		if err := c.report(name, reporters[idx], state); err != nil {
+			restore()
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		} else {
+			c.closeProposals(name, ProposalSuperseded)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Unpin 解除 reporter 的锁定，names 为空时解除全部锁定
+func (c *LatencyClient) Unpin(names ...string) {
+	if len(names) == 0 {
+		c.pins.Clear()
+		return
+	}
+
+	for _, name := range names {
+		c.pins.Delete(name)
+	}
+}
+
+// GetPins 当前锁定中的 reporter 及锁定截止时间
+func (c *LatencyClient) GetPins() map[string]time.Time {
+	pins := map[string]time.Time{}
+
+	c.pins.Range(func(key, value any) bool {
+		if name, ok := key.(string); ok {
+			if until, pinned := c.pinnedUntil(name); pinned {
+				pins[name] = until
+			}
+		}
+		return true
+	})
+
+	return pins
+}
+
 func (c *LatencyClient) AddReporter(name string, reporter Reporter) error {
 	if name == "" || reporter == nil {
 		return ErrInvalidReporter
@@ -561,6 +834,11 @@ func (c *LatencyClient) DelReporter(name string) error {
 		)
 	}
 
+	c.pins.Delete(name)
+	c.history.Delete(name)
+	c.approvals.Delete(name)
+	c.closeProposals(name, ProposalRejected)
+
 	return nil
 }
 
@@ -597,6 +875,13 @@ func (c *LatencyClient) GetInterval() time.Duration {
 }
 
 func (c *LatencyClient) QueryLatency(kwargs map[string]string) (*State, error) {
+	return c.QueryLatencyContext(c.runCtx, kwargs)
+}
+
+// QueryLatencyContext 以临时配置执行一次查询，ctx 取消时中止查询
+func (c *LatencyClient) QueryLatencyContext(
+	ctx context.Context, kwargs map[string]string,
+) (*State, error) {
 	var tmpCfg QueryConfig = *c.cfg.Load().Clone()
 
 	if cfg, exists := kwargs["config"]; exists {
@@ -612,7 +897,7 @@ func (c *LatencyClient) QueryLatency(kwargs map[string]string) (*State, error) {
 	}
 
 	ts := time.Now()
-	latency, err := c.queryLatency(&tmpCfg)
+	latency, err := c.queryLatency(ctx, &tmpCfg)
 	if err != nil {
 		return nil, err
 	}
@@ -2,6 +2,7 @@ package latency4go
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"testing"
 	"time"
@@ -43,3 +44,273 @@ func TestQuery(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestPush(t *testing.T) {
+	client := LatencyClient{notify: make(chan *State, 1)}
+
+	reported := make(chan []string, 10)
+	if err := client.AddReporter("test", func(s *State) error {
+		reported <- s.AddrList
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	go client.runReporter()
+	defer close(client.notify)
+
+	pushed := &State{AddrList: []string{"b", "a"}}
+	if err := client.Push(pushed, time.Now().Add(time.Minute), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if addrs := <-reported; addrs[0] != "b" {
+		t.Fatalf("pushed state mismatch: %v", addrs)
+	}
+
+	if _, pinned := client.GetPins()["test"]; !pinned {
+		t.Fatal("reporter not pinned")
+	}
+
+	if err := client.Push(pushed, time.Time{}, "missing"); err == nil {
+		t.Fatal("push to missing reporter succeeded")
+	}
+
+	// 锁定期间的周期上报被跳过
+	client.notify <- &State{AddrList: []string{"a", "b"}}
+	select {
+	case addrs := <-reported:
+		t.Fatalf("pinned reporter overwritten: %v", addrs)
+	case <-time.After(time.Millisecond * 100):
+	}
+
+	client.Unpin("test")
+	client.notify <- &State{AddrList: []string{"a", "b"}}
+	select {
+	case addrs := <-reported:
+		if addrs[0] != "a" {
+			t.Fatalf("periodic state mismatch: %v", addrs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("unpinned reporter not reported")
+	}
+}
+
+func TestPushFailed(t *testing.T) {
+	client := LatencyClient{notify: make(chan *State, 1)}
+
+	broken := errors.New("broken reporter")
+	reported := make(chan []string, 10)
+	if err := client.AddReporter("broken", func(s *State) error {
+		reported <- s.AddrList
+		return broken
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	go client.runReporter()
+	defer close(client.notify)
+
+	// 上报失败时不锁定 reporter，周期上报继续
+	if err := client.Push(
+		&State{AddrList: []string{"b", "a"}}, time.Now().Add(time.Minute), "broken",
+	); !errors.Is(err, broken) {
+		t.Fatalf("push error mismatch: %v", err)
+	}
+	<-reported
+
+	if pins := client.GetPins(); len(pins) != 0 {
+		t.Fatalf("failed push pinned reporter: %+v", pins)
+	}
+
+	client.notify <- &State{AddrList: []string{"a", "b"}}
+	select {
+	case addrs := <-reported:
+		if addrs[0] != "a" {
+			t.Fatalf("periodic state mismatch: %v", addrs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("periodic report suppressed after failed push")
+	}
+
+	// 上报失败时保持原有锁定
+	until := time.Now().Add(time.Hour)
+	client.pins.Store("broken", until)
+
+	if err := client.Push(
+		&State{AddrList: []string{"b", "a"}}, time.Time{}, "broken",
+	); !errors.Is(err, broken) {
+		t.Fatalf("push error mismatch: %v", err)
+	}
+	<-reported
+
+	if pinned, exist := client.GetPins()["broken"]; !exist || !pinned.Equal(until) {
+		t.Fatalf("previous pin not restored: %+v", client.GetPins())
+	}
+}
+
+func TestRollback(t *testing.T) {
+	client := LatencyClient{notify: make(chan *State, 1)}
+	client.SetHistorySize(3)
+
+	reported := make(chan []string, 10)
+	if err := client.AddReporter("test", func(s *State) error {
+		reported <- s.AddrList
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	go client.runReporter()
+	defer close(client.notify)
+
+	for _, addr := range []string{"a", "b", "c", "d"} {
+		client.notify <- &State{AddrList: []string{addr}}
+		<-reported
+	}
+
+	if history := client.GetHistory("test"); len(history) != 3 ||
+		history[0].AddrList[0] != "b" {
+		t.Fatalf("report history mismatch: %+v", history)
+	}
+
+	if _, err := client.Rollback("test", 3, 0); err == nil {
+		t.Fatal("rollback beyond history succeeded")
+	}
+
+	// 连续回退逐次回到更早的状态，而非在最近两个状态间来回切换
+	for _, addr := range []string{"c", "b"} {
+		state, err := client.Rollback("test", 1, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if addrs := <-reported; state.AddrList[0] != addr || addrs[0] != addr {
+			t.Fatalf("rollback state mismatch: %v, expect %s", addrs, addr)
+		}
+
+		if history := client.GetHistory("test"); history[len(history)-1] != state {
+			t.Fatalf("rollback history mismatch: %+v", history)
+		}
+	}
+
+	if _, err := client.Rollback("test", 1, 0); err == nil {
+		t.Fatal("rollback beyond rewound history succeeded")
+	}
+
+	if _, pinned := client.GetPins()["test"]; !pinned {
+		t.Fatal("reporter not held after rollback")
+	}
+}
+
+func TestApproval(t *testing.T) {
+	client := LatencyClient{notify: make(chan *State, 1)}
+
+	reported := make(chan []string, 10)
+	if err := client.AddReporter("test", func(s *State) error {
+		reported <- s.AddrList
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	proposals := make(chan *Proposal, 10)
+	client.OnProposal(func(p *Proposal) { proposals <- p })
+
+	if err := client.SetApproval(
+		"unknown", time.Minute,
+	); !errors.Is(err, ErrInvalidReporter) {
+		t.Fatalf("approval set for unknown reporter: %v", err)
+	}
+
+	if err := client.SetApproval("test", time.Millisecond*200); err != nil {
+		t.Fatal(err)
+	}
+
+	go client.runReporter()
+	defer close(client.notify)
+
+	client.notify <- &State{AddrList: []string{"a", "b"}}
+	p := <-proposals
+	if p.Status != ProposalPending || len(p.Diff) != 2 {
+		t.Fatalf("proposal mismatch: %+v", p)
+	}
+
+	if _, err := client.Approve(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	if p = <-proposals; p.Status != ProposalApproved {
+		t.Fatalf("proposal not approved: %+v", p)
+	}
+	if addrs := <-reported; addrs[0] != "a" {
+		t.Fatalf("approved state mismatch: %v", addrs)
+	}
+
+	client.notify <- &State{AddrList: []string{"b", "a"}}
+	p = <-proposals
+	if len(p.Current) != 2 || p.Current[0] != "a" {
+		t.Fatalf("proposal current mismatch: %+v", p)
+	}
+
+	select {
+	case p = <-proposals:
+		if p.Status != ProposalExpired {
+			t.Fatalf("proposal not expired: %+v", p)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("wait proposal expire timeout")
+	}
+
+	if _, err := client.Approve(p.ID); err == nil {
+		t.Fatal("expired proposal approved")
+	}
+
+	select {
+	case addrs := <-reported:
+		t.Fatalf("unapproved state reported: %v", addrs)
+	default:
+	}
+
+	// 人工推送不经审批，并结束待审批提案
+	client.notify <- &State{AddrList: []string{"c", "a"}}
+	if p = <-proposals; p.Status != ProposalPending {
+		t.Fatalf("proposal mismatch: %+v", p)
+	}
+
+	if err := client.Push(
+		&State{AddrList: []string{"b"}}, time.Time{}, "test",
+	); err != nil {
+		t.Fatal(err)
+	}
+	if addrs := <-reported; addrs[0] != "b" {
+		t.Fatalf("pushed state mismatch: %v", addrs)
+	}
+	if p = <-proposals; p.Status != ProposalSuperseded {
+		t.Fatalf("proposal not superseded by push: %+v", p)
+	}
+
+	// 上报失败的提案通知失败状态
+	if err := client.SetApproval("test", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.AddReporter("broken", func(*State) error {
+		return errors.New("report failed")
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetApproval("broken", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	client.notify <- &State{AddrList: []string{"c"}}
+	if p = <-proposals; p.Reporter != "broken" || p.Status != ProposalPending {
+		t.Fatalf("proposal mismatch: %+v", p)
+	}
+
+	if p, err := client.Approve(p.ID); err == nil || p.Status != ProposalFailed {
+		t.Fatalf("failed report approved: %+v, %v", p, err)
+	}
+	if p = <-proposals; p.Status != ProposalFailed {
+		t.Fatalf("failed proposal status mismatch: %+v", p)
+	}
+}
@@ -0,0 +1,112 @@
+package ctl
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+func cmdApproval(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+	name := cmd.KwArgs["plugin"]
+
+	ttl := latency4go.DefaultProposalTTL
+	if v, exist := cmd.KwArgs["ttl"]; exist {
+		var err error
+		if ttl, err = time.ParseDuration(v); err != nil || ttl <= 0 {
+			result.Rtn = 1
+			result.Message = fmt.Sprintf("invalid approval ttl: %s", v)
+			return fmt.Errorf("%w: invalid approval ttl", ErrInvalidArgument)
+		}
+	}
+
+	if off, _ := strconv.ParseBool(cmd.KwArgs["off"]); off {
+		ttl = 0
+	}
+
+	if err := client.SetApproval(name, ttl); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf("set approval mode failed: %+v", err)
+		return err
+	}
+
+	// 保存审批模式，LatencyClient 重新启动或插件重新加载后保持
+	if ttl > 0 {
+		svr.approvals.Store(name, ttl)
+	} else {
+		svr.approvals.Delete(name)
+	}
+
+	var message string
+	if ttl > 0 {
+		message = fmt.Sprintf("%s approval mode on, proposal expire in %s", name, ttl)
+	} else {
+		message = name + " approval mode off"
+	}
+
+	result.Values[VKeyApproval] = client.GetApprovals()
+	result.Message = message
+
+	svr.alert(slog.LevelWarn, "approval", message)
+
+	return nil
+}
+
+func cmdProposals(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+
+	result.Values[VKeyProposal] = client.GetProposals()
+	result.Values[VKeyApproval] = client.GetApprovals()
+	result.Message = "get proposals finished"
+
+	return nil
+}
+
+// cmdApprove 处理 approve / reject 命令
+func cmdApprove(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+	id, _ := strconv.ParseUint(cmd.KwArgs["id"], 10, 64)
+
+	var (
+		p   *latency4go.Proposal
+		err error
+	)
+	if cmd.Name == "approve" {
+		p, err = client.Approve(id)
+	} else {
+		p, err = client.Reject(id)
+	}
+
+	if p != nil {
+		result.Values[VKeyProposal] = []*latency4go.Proposal{p}
+
+		message := fmt.Sprintf(
+			"proposal %d of %s %s: %v", p.ID, p.Reporter, p.Status, p.State.AddrList,
+		)
+		if cmd.session != nil {
+			message += " by " + cmd.session.remote
+		}
+		if err != nil {
+			message += ", report failed: " + err.Error()
+		}
+
+		svr.alert(slog.LevelWarn, "approval", message)
+	}
+
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"%s proposal failed: %s", cmd.Name, strings.ReplaceAll(err.Error(), "\n", "; "),
+		)
+		return err
+	}
+
+	result.Message = fmt.Sprintf("proposal %d %s", p.ID, p.Status)
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+var (
+	ErrAuditDisabled = errors.New("audit log not enabled")
+)
+
+const (
+	// defaultAuditCount audit 命令默认返回的记录数
+	defaultAuditCount = 20
+	// maxAuditCount audit 命令单次允许返回的最大记录数
+	maxAuditCount = 1000
+	// maxAuditLine 单条审计记录的最大长度，超出的记录查询时跳过
+	maxAuditLine = 1 << 20
+)
+
+// auditConfigCommands 需记录变更前后 QueryConfig 的命令
+var auditConfigCommands = map[string]bool{
+	"config": true,
+	"start":  true,
+}
+
+// AuditEntry 审计日志条目，以 JSON Lines 格式追加写入审计文件
+type AuditEntry struct {
+	Timestamp time.Time
+	MsgID     uint64
+	Handler   string `json:",omitempty"`
+	Remote    string `json:",omitempty"`
+	Identity  string `json:",omitempty"`
+	Role      Role
+	Command   string
+	KwArgs    map[string]string `json:",omitempty"`
+	Rtn       int
+	Message   string                  `json:",omitempty"`
+	Before    *latency4go.QueryConfig `json:",omitempty"`
+	After     *latency4go.QueryConfig `json:",omitempty"`
+}
+
+type ctlAuditor struct {
+	lock sync.Mutex
+	path string
+	file *os.File
+}
+
+func newCtlAuditor(path string) (*ctlAuditor, error) {
+	file, err := os.OpenFile(
+		path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ctlAuditor{path: path, file: file}, nil
+}
+
+func (audit *ctlAuditor) record(entry *AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	_, err = audit.file.Write(append(data, '\n'))
+
+	return err
+}
+
+// recent 返回最近 count 条满足过滤条件的审计记录，按时间正序排列
+func (audit *ctlAuditor) recent(
+	count int, filter func(*AuditEntry) bool,
+) ([]*AuditEntry, error) {
+	if count <= 0 || count > maxAuditCount {
+		return nil, fmt.Errorf(
+			"%w: audit count %d out of range [1, %d]",
+			ErrInvalidArgument, count, maxAuditCount,
+		)
+	}
+
+	file, err := os.Open(audit.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// 环形缓冲仅保留最后 count 条记录
+	entries := make([]*AuditEntry, 0, count)
+	next := 0
+
+	rd := bufio.NewReader(file)
+	line := []byte{}
+	oversized := false
+
+	for {
+		frag, isPrefix, err := rd.ReadLine()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if len(line)+len(frag) > maxAuditLine {
+			oversized = true
+		} else if !oversized {
+			line = append(line, frag...)
+		}
+
+		if isPrefix {
+			continue
+		}
+
+		data, skip := line, oversized
+		line, oversized = line[:0], false
+
+		if skip {
+			slog.Warn(
+				"oversized audit entry skipped",
+				slog.String("path", audit.path),
+			)
+			continue
+		}
+
+		var entry AuditEntry
+		if err := json.Unmarshal(data, &entry); err != nil {
+			continue
+		}
+
+		if filter != nil && !filter(&entry) {
+			continue
+		}
+
+		if len(entries) < count {
+			entries = append(entries, &entry)
+		} else {
+			entries[next] = &entry
+		}
+		next = (next + 1) % count
+	}
+
+	if len(entries) == count {
+		entries = append(entries[next:], entries[:next]...)
+	}
+
+	return entries, nil
+}
+
+func (audit *ctlAuditor) close() error {
+	audit.lock.Lock()
+	defer audit.lock.Unlock()
+
+	return audit.file.Close()
+}
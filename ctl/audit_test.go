@@ -0,0 +1,86 @@
+package ctl
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAuditRecent(t *testing.T) {
+	audit, err := newCtlAuditor(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.close()
+
+	for idx := range 10 {
+		identity := "ops"
+		if idx%2 == 0 {
+			identity = "viewer"
+		}
+
+		if err := audit.record(&AuditEntry{
+			MsgID:    uint64(idx + 1),
+			Identity: identity,
+			Command:  "state",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := audit.recent(3, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 || entries[0].MsgID != 8 || entries[2].MsgID != 10 {
+		t.Fatalf("invalid recent entries: %+v", entries)
+	}
+
+	entries, err = audit.recent(20, func(entry *AuditEntry) bool {
+		return entry.Identity == "ops"
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 5 || entries[0].MsgID != 2 || entries[4].MsgID != 10 {
+		t.Fatalf("invalid filtered entries: %+v", entries)
+	}
+}
+
+func TestAuditRecentLimit(t *testing.T) {
+	audit, err := newCtlAuditor(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer audit.close()
+
+	if err := audit.record(&AuditEntry{MsgID: 1, Command: "state"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超长记录跳过，不影响前后记录的查询
+	if _, err := audit.file.WriteString(
+		`{"MsgID":2,"Message":"` + strings.Repeat("x", maxAuditLine) + "\"}\n",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := audit.record(&AuditEntry{MsgID: 3, Command: "state"}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := audit.recent(10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].MsgID != 1 || entries[1].MsgID != 3 {
+		t.Fatalf("oversized entry not skipped: %+v", entries)
+	}
+
+	for _, count := range []int{0, maxAuditCount + 1} {
+		if _, err := audit.recent(count, nil); !errors.Is(err, ErrInvalidArgument) {
+			t.Fatalf("invalid audit count accepted: %d, %v", count, err)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package ctl
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+const (
+	// CTL_AUTH_ENV 客户端认证信息环境变量，格式为：{identity}:{secret}
+	CTL_AUTH_ENV = "LATENCY_CTL_AUTH"
+)
+
+var (
+	ErrAuthRequired = errors.New("authentication required")
+	ErrAuthFailed   = errors.New("authentication failed")
+)
+
+// authData 认证握手消息内容
+//
+//	client -> server: {Identity}
+//	server -> client: {Nonce}
+//	client -> server: {Identity, Signature}
+//	server -> client: {Rtn, Message}
+type authData struct {
+	Identity  string `json:",omitempty"`
+	Nonce     string `json:",omitempty"`
+	Signature string `json:",omitempty"`
+	Rtn       int
+	Message   string `json:",omitempty"`
+}
+
+// authKeyFile 服务端密钥文件格式:
+//
+//	[keys]
+//	ops = "secret"
+type authKeyFile struct {
+	Keys map[string]string `toml:"keys"`
+}
+
+type ctlAuthenticator struct {
+	keys map[string]string
+}
+
+func loadAuthenticator(path string) (*ctlAuthenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keyFile authKeyFile
+	if err = toml.Unmarshal(data, &keyFile); err != nil {
+		return nil, err
+	}
+
+	if len(keyFile.Keys) <= 0 {
+		return nil, fmt.Errorf("no auth keys found in %s", path)
+	}
+
+	return &ctlAuthenticator{keys: keyFile.Keys}, nil
+}
+
+func newNonce() (string, error) {
+	buff := make([]byte, 32)
+
+	if _, err := rand.Read(buff); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buff), nil
+}
+
+func signChallenge(secret, identity, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(identity))
+	mac.Write([]byte{':'})
+	mac.Write([]byte(nonce))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (auth *ctlAuthenticator) verify(identity, nonce, signature string) bool {
+	secret, exist := auth.keys[identity]
+	if !exist || nonce == "" {
+		return false
+	}
+
+	expect := signChallenge(secret, identity, nonce)
+
+	return hmac.Equal([]byte(expect), []byte(signature))
+}
+
+type authCredential struct {
+	identity string
+	secret   string
+}
+
+// parseAuthConn 从连接字串 {identity}:{secret}@{addr} 或环境变量中解析认证信息
+func parseAuthConn(conn string) (string, *authCredential) {
+	var auth string
+
+	if idx := strings.LastIndex(conn, "@"); idx >= 0 {
+		auth, conn = conn[:idx], conn[idx+1:]
+	} else {
+		auth = os.Getenv(CTL_AUTH_ENV)
+	}
+
+	identity, secret, found := strings.Cut(auth, ":")
+	if !found || identity == "" {
+		return conn, nil
+	}
+
+	return conn, &authCredential{identity: identity, secret: secret}
+}
@@ -0,0 +1,63 @@
+package ctl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTcpAuthenticate(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys.toml")
+	if err := os.WriteFile(
+		keyFile, []byte("[keys]\nops = \"secret\"\n"), 0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0?keys=" + keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	addr := hdl.listen.Addr().String()
+
+	dial := tcpDialer(addr, nil)
+
+	client, err := newCtlTcpClient(addr, dial, &authCredential{
+		identity: "ops", secret: "secret",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.conn.Close()
+
+	if _, err = newCtlTcpClient(addr, dial, &authCredential{
+		identity: "ops", secret: "wrong",
+	}, nil); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("wrong secret authenticated: %+v", err)
+	}
+
+	if _, err = newCtlTcpClient(addr, dial, &authCredential{
+		identity: "nobody", secret: "secret",
+	}, nil); !errors.Is(err, ErrAuthFailed) {
+		t.Fatalf("unknown identity authenticated: %+v", err)
+	}
+}
+
+func TestParseAuthConn(t *testing.T) {
+	t.Setenv(CTL_AUTH_ENV, "env:envsecret")
+
+	addr, cred := parseAuthConn("ops:p@ss@127.0.0.1:45678")
+	if addr != "127.0.0.1:45678" || cred == nil ||
+		cred.identity != "ops" || cred.secret != "p@ss" {
+		t.Fatalf("parse conn auth failed: %s %+v", addr, cred)
+	}
+
+	addr, cred = parseAuthConn("127.0.0.1:45678")
+	if addr != "127.0.0.1:45678" || cred == nil || cred.identity != "env" {
+		t.Fatalf("parse env auth failed: %s %+v", addr, cred)
+	}
+}
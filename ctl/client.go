@@ -3,21 +3,57 @@ package ctl
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/frozenpine/latency4go"
 	"github.com/frozenpine/msgqueue/channel"
 	"github.com/frozenpine/msgqueue/core"
-	"github.com/gofrs/uuid"
 )
 
+var (
+	ErrCtlClientClosed       = errors.New("ctl client closed")
+	ErrCtlClientDisconnected = errors.New("ctl client disconnected")
+)
+
+// ConnState 客户端与控制台服务端的连接状态
+type ConnState uint8
+
+const (
+	ConnConnected ConnState = iota
+	ConnReconnecting
+	ConnClosed
+)
+
+var connStateNames = [...]string{"connected", "reconnecting", "closed"}
+
+func (s ConnState) String() string {
+	if int(s) < len(connStateNames) {
+		return connStateNames[s]
+	}
+
+	return fmt.Sprintf("ConnState(%d)", s)
+}
+
 type CtlClient interface {
 	core.Consumer[*Message]
 
 	Start()
 	Command(cmd *Command) error
+	// Call 发送命令并等待同一 msgID 的执行结果，结果仅返回给调用方，不再投递至 MessageLoop
+	Call(ctx context.Context, cmd *Command) (*Result, error)
+	GetConnState() ConnState
+	// GetRTT 最近一次心跳的往返时延，未启用心跳时为 0
+	GetRTT() time.Duration
+	// WatchConnState 注册连接状态变化回调，相同 name 的回调将被替换
+	WatchConnState(name string, fn func(ConnState))
+	// GetServerHello 握手获得的服务端信息，未经握手的连接（如 IPC）为 nil
+	GetServerHello() *Hello
 	GetCmdSeq() uint64
 	MessageLoop(
 		name string,
@@ -26,6 +62,12 @@ type CtlClient interface {
 		handleResult func(*Result) error,
 		postRun func() error,
 	) error
+	// EventLoop 处理已订阅主题的广播事件
+	EventLoop(name string, handleEvent func(*Event) error) error
+	// LogLoop 处理已订阅的服务端日志
+	LogLoop(name string, handleLog func(*LogRecord) error) error
+	// ProgressLoop 处理命令执行完成前的进度通知
+	ProgressLoop(name string, handleProgress func(*Progress) error) error
 }
 
 type ctlBaseClient struct {
@@ -33,20 +75,74 @@ type ctlBaseClient struct {
 	name   string
 	cmdSeq atomic.Uint64
 
-	msgLoopSubs sync.Map
+	pendingCalls sync.Map
+
+	connState    atomic.Uint32
+	connWatchers sync.Map
+	rtt          atomic.Int64
+	server       atomic.Pointer[Hello]
+
+	// 已发送的订阅命令，按主题保留最后一次，重连后重新发送
+	subCommands sync.Map
 }
 
 func (c *ctlBaseClient) Name() string {
 	return c.name
 }
 
+func (c *ctlBaseClient) GetConnState() ConnState {
+	return ConnState(c.connState.Load())
+}
+
+func (c *ctlBaseClient) GetRTT() time.Duration {
+	return time.Duration(c.rtt.Load())
+}
+
+func (c *ctlBaseClient) GetServerHello() *Hello {
+	return c.server.Load()
+}
+
+func (c *ctlBaseClient) WatchConnState(name string, fn func(ConnState)) {
+	if fn == nil {
+		c.connWatchers.Delete(name)
+	} else {
+		c.connWatchers.Store(name, fn)
+	}
+}
+
+func (c *ctlBaseClient) setConnState(state ConnState) {
+	if ConnState(c.connState.Swap(uint32(state))) == state {
+		return
+	}
+
+	slog.Info(
+		"ctl client conn state changed",
+		slog.String("name", c.name),
+		slog.String("state", state.String()),
+	)
+
+	c.connWatchers.Range(func(_, value any) bool {
+		value.(func(ConnState))(state)
+		return true
+	})
+}
+
 func (c *ctlBaseClient) createCmdMessage(cmd *Command) (*Message, error) {
+	if !c.server.Load().Supports(cmd.Name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCommand, cmd.Name)
+	}
+
 	cmdData, err := json.Marshal(cmd)
 
 	if err != nil {
 		return nil, err
 	}
 
+	switch cmd.Name {
+	case "subscribe", "unsubscribe":
+		c.subCommands.Store(cmd.KwArgs["topic"], cmd)
+	}
+
 	return &Message{
 		msgID:   c.cmdSeq.Add(1),
 		msgType: MsgCommand,
@@ -54,25 +150,113 @@ func (c *ctlBaseClient) createCmdMessage(cmd *Command) (*Message, error) {
 	}, nil
 }
 
+// resubscribe 重连建立新会话后重新发送订阅命令
+func (c *ctlBaseClient) resubscribe(send func(*Message) error) {
+	c.subCommands.Range(func(_, value any) bool {
+		msg, err := c.createCmdMessage(value.(*Command))
+		if err == nil {
+			err = send(msg)
+		}
+
+		if err != nil {
+			slog.Error(
+				"resend subscribe command failed",
+				slog.Any("error", err),
+				slog.Any("cmd", value),
+			)
+		}
+
+		return true
+	})
+}
+
 func (c *ctlBaseClient) GetCmdSeq() uint64 {
 	return c.cmdSeq.Load()
 }
 
-func (c *ctlBaseClient) closeLoop() {
-	c.msgLoopSubs.Range(func(key, value any) bool {
-		if sub, ok := key.(uuid.UUID); !ok {
-			slog.Error(
-				"invalid message loop sub id",
-				slog.Any("key", key),
-			)
-		} else {
-			c.UnSubscribe(sub)
+func (c *ctlBaseClient) call(
+	ctx context.Context, cmd *Command, send func(*Message) error,
+) (*Result, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	msg, err := c.createCmdMessage(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	wait := make(chan *Message, 1)
+	c.pendingCalls.Store(msg.msgID, wait)
+	defer c.pendingCalls.Delete(msg.msgID)
+
+	if err = send(msg); err != nil {
+		return nil, err
+	}
+
+	select {
+	case rsp, ok := <-wait:
+		if !ok {
+			return nil, ErrCtlClientClosed
+		}
+
+		return rsp.GetResult()
+	case <-ctx.Done():
+		c.abandonCall(msg.msgID, send)
+		return nil, ctx.Err()
+	}
+}
+
+// abandonCall 放弃等待结果时通知服务端取消执行中的命令，服务端不支持 cancel 时忽略
+func (c *ctlBaseClient) abandonCall(id uint64, send func(*Message) error) {
+	msg, err := c.createCmdMessage(&Command{
+		Name:   "cancel",
+		KwArgs: map[string]string{"id": strconv.FormatUint(id, 10)},
+	})
+	if err != nil {
+		return
+	}
+
+	if err = send(msg); err != nil {
+		slog.Warn(
+			"send cancel command failed",
+			slog.Any("error", err),
+			slog.Uint64("msg_id", id),
+		)
+	}
+}
+
+// resolveCall 将执行结果投递给等待该 msgID 的 Call 调用，返回结果是否已投递
+func (c *ctlBaseClient) resolveCall(msg *Message) bool {
+	if msg.GetType() != MsgResult {
+		return false
+	}
+
+	wait, exist := c.pendingCalls.LoadAndDelete(msg.msgID)
+	if exist {
+		wait.(chan *Message) <- msg
+	}
+
+	return exist
+}
+
+// cancelCalls 连接断开时结束所有等待中的 Call 调用
+func (c *ctlBaseClient) cancelCalls() {
+	c.pendingCalls.Range(func(key, _ any) bool {
+		if wait, exist := c.pendingCalls.LoadAndDelete(key); exist {
+			close(wait.(chan *Message))
 		}
 
 		return true
 	})
+}
 
-	c.msgLoopSubs.Clear()
+// closeLoop 连接最终关闭后结束等待中的 Call 调用并释放消息通道，
+// 订阅由通道的分发协程停止分发后关闭，避免与分发中的发送竞争，MessageLoop 等随之退出
+func (c *ctlBaseClient) closeLoop() {
+	c.cancelCalls()
+
+	c.MemoChannel.Release()
 }
 
 func (c *ctlBaseClient) MessageLoop(
@@ -107,13 +291,9 @@ func (c *ctlBaseClient) MessageLoop(
 			slog.String("sub_id", subId.String()),
 		)
 
-		c.msgLoopSubs.Store(subId, struct{}{})
-
+		// 订阅通道关闭后退出，订阅已由关闭方移除
 		defer func() {
-			defer func() {
-				c.UnSubscribe(subId)
-				close(closeWait)
-			}()
+			defer close(closeWait)
 
 			if postRun == nil {
 				return
@@ -181,6 +361,8 @@ func (c *ctlBaseClient) MessageLoop(
 						slog.String("name", name),
 					)
 				}
+			case MsgEvent, MsgLog, MsgProgress:
+				// 事件、日志及执行进度由 EventLoop、LogLoop、ProgressLoop 处理
 			default:
 				slog.Warn(
 					"unsupported return msg from ctl server",
@@ -194,3 +376,96 @@ func (c *ctlBaseClient) MessageLoop(
 
 	return nil
 }
+
+func (c *ctlBaseClient) EventLoop(
+	name string, handleEvent func(*Event) error,
+) error {
+	if handleEvent == nil {
+		return errors.New("no event handler")
+	}
+
+	c.typedLoop(name, MsgEvent, func(msg *Message) error {
+		event, err := msg.GetEvent()
+		if err != nil {
+			return err
+		}
+
+		return handleEvent(event)
+	})
+
+	return nil
+}
+
+func (c *ctlBaseClient) LogLoop(
+	name string, handleLog func(*LogRecord) error,
+) error {
+	if handleLog == nil {
+		return errors.New("no log handler")
+	}
+
+	c.typedLoop(name, MsgLog, func(msg *Message) error {
+		record, err := msg.GetLog()
+		if err != nil {
+			return err
+		}
+
+		return handleLog(record)
+	})
+
+	return nil
+}
+
+func (c *ctlBaseClient) ProgressLoop(
+	name string, handleProgress func(*Progress) error,
+) error {
+	if handleProgress == nil {
+		return errors.New("no progress handler")
+	}
+
+	c.typedLoop(name, MsgProgress, func(msg *Message) error {
+		progress, err := msg.GetProgress()
+		if err != nil || progress == nil {
+			return err
+		}
+
+		return handleProgress(progress)
+	})
+
+	return nil
+}
+
+// typedLoop 以独立订阅处理指定类型的消息
+func (c *ctlBaseClient) typedLoop(
+	name string, msgType messageType, handle func(*Message) error,
+) {
+	go func() {
+		subId, notify := c.Subscribe(name, core.Quick)
+
+		slog.Info(
+			"typed loop get new subscribe",
+			slog.Any("name", name),
+			slog.String("type", msgType.String()),
+			slog.String("sub_id", subId.String()),
+		)
+
+		// 订阅通道关闭后退出，订阅已由关闭方移除
+		for msg := range notify {
+			if msg.GetType() != msgType {
+				continue
+			}
+
+			if err := handle(msg); err != nil {
+				slog.Error(
+					"typed loop handle message failed",
+					slog.Any("error", err),
+					slog.String("name", name),
+				)
+			}
+		}
+
+		slog.Info(
+			"ctl client typed loop exit",
+			slog.String("name", name),
+		)
+	}()
+}
@@ -1,9 +1,11 @@
 package ctl
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
+	"sync/atomic"
 	"time"
 
 	ipc "github.com/james-barrow/golang-ipc"
@@ -14,6 +16,7 @@ type CtlIpcClient struct {
 
 	ipcClient *ipc.Client
 	waitConn  chan struct{}
+	connected atomic.Bool
 }
 
 func (client *CtlIpcClient) Start() {
@@ -22,14 +25,15 @@ func (client *CtlIpcClient) Start() {
 			ipcMsg, err := client.ipcClient.Read()
 
 			if err != nil {
-				if client.ipcClient.StatusCode() == ipc.Closed {
+				switch client.ipcClient.StatusCode() {
+				case ipc.Closed, ipc.Timeout:
 					slog.Info(
 						"ipc client closed",
 						slog.Any("error", err),
 						slog.String("status", client.ipcClient.Status()),
 					)
 
-					break
+					goto EXIT
 				}
 
 				slog.Error(
@@ -47,7 +51,15 @@ func (client *CtlIpcClient) Start() {
 						slog.Any("error", err),
 						slog.Any("ipc_msg", ipcMsg),
 					)
-				} else if err = client.MemoChannel.Publish(
+					continue
+				}
+
+				// Call 的结果由调用方处理，不再重复投递
+				if client.resolveCall(&msg) {
+					continue
+				}
+
+				if err = client.MemoChannel.Publish(
 					&msg, time.Second*5,
 				); err != nil {
 					slog.Error(
@@ -64,11 +76,34 @@ func (client *CtlIpcClient) Start() {
 
 				switch client.ipcClient.StatusCode() {
 				case ipc.Connected:
-					close(client.waitConn)
+					if client.connected.Swap(true) {
+						// 重连成功后重新获取服务端信息
+						go func() {
+							if err := client.Command(&Command{
+								Name: "info",
+							}); err != nil {
+								slog.Error(
+									"make reconnected info command failed",
+									slog.Any("error", err),
+								)
+							}
+						}()
+					} else {
+						close(client.waitConn)
+					}
+
+					client.setConnState(ConnConnected)
+				case ipc.ReConnecting:
+					client.setConnState(ConnReconnecting)
+					client.cancelCalls()
 				}
 			}
 		}
 
+	EXIT:
+		client.cancelCalls()
+		client.setConnState(ConnClosed)
+
 		slog.Info("ipc channel closed")
 	}()
 
@@ -81,20 +116,28 @@ func (client *CtlIpcClient) Start() {
 	}
 }
 
-func (client *CtlIpcClient) Command(cmd *Command) error {
+func (client *CtlIpcClient) writeMsg(msg *Message) error {
 	<-client.waitConn
 
-	msg, err := client.createCmdMessage(cmd)
+	data, err := json.Marshal(msg)
 	if err != nil {
 		return err
 	}
 
-	data, err := json.Marshal(msg)
+	return client.ipcClient.Write(1, data)
+}
+
+func (client *CtlIpcClient) Command(cmd *Command) error {
+	msg, err := client.createCmdMessage(cmd)
 	if err != nil {
 		return err
 	}
 
-	return client.ipcClient.Write(1, data)
+	return client.writeMsg(msg)
+}
+
+func (client *CtlIpcClient) Call(ctx context.Context, cmd *Command) (*Result, error) {
+	return client.call(ctx, cmd, client.writeMsg)
 }
 
 func (client *CtlIpcClient) Release() {
@@ -140,6 +183,7 @@ func NewCtlIpcClient(conn string) (*CtlIpcClient, error) {
 		ipcClient: client,
 		waitConn:  make(chan struct{}),
 	}
+	instance.connState.Store(uint32(ConnReconnecting))
 
 	return instance, nil
 }
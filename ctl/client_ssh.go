@@ -144,41 +144,63 @@ func NewCtlSshTcpClient(conn string) (*CtlSshTcpClient, error) {
 		sshAuth = append(sshAuth, ssh.PublicKeys(sign))
 	}
 
-	sshClient, err := ssh.Dial("tcp", sshHost, &ssh.ClientConfig{
+	conn, opts, err := splitConnOptions(match[connIdx])
+	if err != nil {
+		return nil, err
+	}
+
+	ctlAddr, cred := parseAuthConn(conn)
+
+	sshCfg := ssh.ClientConfig{
 		User:            sshUser,
 		Auth:            sshAuth,
 		Timeout:         time.Second * 15,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
-	})
-	if err != nil {
-		return nil, err
 	}
 
-	pipe, err := sshClient.Dial("tcp", match[connIdx])
-	if err != nil {
-		return nil, err
-	}
+	// 每次(重新)连接均重建 ssh 隧道及本地转发
+	dial := func() (net.Conn, error) {
+		sshClient, err := ssh.Dial("tcp", sshHost, &sshCfg)
+		if err != nil {
+			return nil, err
+		}
 
-	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
-	if err != nil {
-		return nil, err
-	}
+		pipe, err := sshClient.Dial("tcp", ctlAddr)
+		if err != nil {
+			sshClient.Close()
+			return nil, err
+		}
 
-	lsnr, err := net.ListenTCP("tcp", addr)
-	if err != nil {
-		return nil, err
-	} else {
-		slog.Info(
-			"open local listenner for forwarding",
-			slog.Any("lsnr", lsnr.Addr()),
-		)
-	}
+		addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:0")
+		if err != nil {
+			sshClient.Close()
+			return nil, err
+		}
+
+		lsnr, err := net.ListenTCP("tcp", addr)
+		if err != nil {
+			sshClient.Close()
+			return nil, err
+		} else {
+			slog.Info(
+				"open local listenner for forwarding",
+				slog.Any("lsnr", lsnr.Addr()),
+			)
+		}
+
+		go forward(sshClient, lsnr, pipe)
+
+		local, err := net.DialTimeout("tcp", lsnr.Addr().String(), time.Second*10)
+		if err != nil {
+			lsnr.Close()
+			return nil, err
+		}
 
-	go forward(sshClient, lsnr, pipe)
+		return local, nil
+	}
 
-	inner, err := NewCtlTcpClient(lsnr.Addr().String())
+	inner, err := newCtlTcpClient(sshHost+"?"+ctlAddr, dial, cred, opts)
 	if err != nil {
-		lsnr.Close()
 		return nil, err
 	}
 
@@ -1,36 +1,434 @@
 package ctl
 
 import (
-	"bufio"
-	"encoding/json"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	reconnectBackoffMin = time.Millisecond * 500
+	reconnectBackoffMax = time.Second * 30
+)
+
+// ctlDialer 建立到控制台服务端的底层连接，断线重连时重复调用
+type ctlDialer func() (net.Conn, error)
+
+func tcpDialer(addr string, tlsCfg *tls.Config) ctlDialer {
+	return func() (net.Conn, error) {
+		dialer := net.Dialer{
+			Timeout: time.Second * 10,
+		}
+
+		if tlsCfg != nil {
+			return tls.DialWithDialer(&dialer, "tcp", addr, tlsCfg)
+		}
+
+		return dialer.Dial("tcp", addr)
+	}
+}
+
+func unixDialer(path string) ctlDialer {
+	return func() (net.Conn, error) {
+		return net.DialTimeout("unix", path, time.Second*10)
+	}
+}
+
 type CtlTcpClient struct {
 	ctlBaseClient
 
-	conn net.Conn
+	dial      ctlDialer
+	cred      *authCredential
+	reconnect bool
+	// closed 服务端通知的断开原因，收到后不再自动重连
+	closed    atomic.Pointer[closeData]
+	heartbeat time.Duration
+	framing   framing
+	maxSize   int
+	seen      atomic.Int64
+	done      chan struct{}
+	closeOnce sync.Once
+
+	connLock sync.RWMutex
+	conn     net.Conn
+	codec    *frameCodec
+}
+
+func (c *CtlTcpClient) readMsg() (*Message, error) {
+	return c.codec.readMsg()
+}
+
+func (c *CtlTcpClient) writeMsg(msg *Message) error {
+	c.connLock.RLock()
+	conn, codec := c.conn, c.codec
+	c.connLock.RUnlock()
+
+	frame, err := codec.encode(msg)
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(frame)
+	return err
+}
+
+// send 发送命令消息，连接断开或重连过程中直接返回错误
+func (c *CtlTcpClient) send(msg *Message) error {
+	if c.GetConnState() != ConnConnected {
+		return ErrCtlClientDisconnected
+	}
+
+	if err := c.writeMsg(msg); err != nil {
+		// 关闭连接以触发接收协程的重连流程
+		c.closeConn()
+		return err
+	}
+
+	return nil
+}
+
+func (c *CtlTcpClient) closeConn() {
+	c.connLock.RLock()
+	defer c.connLock.RUnlock()
+
+	if c.conn != nil {
+		c.conn.Close()
+	}
+}
+
+// connect 建立连接并完成认证握手，仅在构造或接收协程中调用
+func (c *CtlTcpClient) connect() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+
+	c.connLock.Lock()
+	c.conn, c.codec = conn, newFrameCodec(conn, c.maxSize)
+	c.connLock.Unlock()
+
+	c.seen.Store(time.Now().UnixNano())
+
+	if c.framing == framingLength {
+		if err = c.handshake(); errors.Is(err, ErrLegacyCtlServer) {
+			slog.Warn(
+				"ctl server not support handshake, fallback to line framing",
+				slog.String("name", c.name),
+			)
+
+			conn.Close()
+			c.framing = framingLine
+			c.server.Store(newLegacyHello())
+
+			return c.connect()
+		} else if err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	if err = c.authenticate(c.cred); err != nil {
+		conn.Close()
+		return err
+	}
+
+	c.setConnState(ConnConnected)
+
+	return nil
+}
+
+// handshake 交换协议版本及能力，协商分帧方式及消息长度上限
+func (c *CtlTcpClient) handshake() error {
+	c.conn.SetReadDeadline(time.Now().Add(ctlHelloTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	hello := newHello()
+	hello.Framing = c.framing
+	hello.MaxSize = c.maxSize
+
+	msg, err := newHelloMessage(hello)
+	if err != nil {
+		return err
+	}
+
+	if err = c.writeMsg(msg); err != nil {
+		return err
+	}
+
+	for {
+		rsp, err := c.readMsg()
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return ErrLegacyCtlServer
+		} else if errors.Is(err, ErrInvalidMsgData) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		switch rsp.GetType() {
+		case MsgHello:
+			hello, err := rsp.getHello()
+			if err != nil {
+				return err
+			} else if hello == nil || hello.MaxSize <= 0 {
+				return fmt.Errorf("%w: invalid hello msg", ErrInvalidMsgData)
+			}
+
+			if err = hello.compatible(); err != nil {
+				return err
+			}
+
+			c.codec.setFraming(hello.Framing, hello.MaxSize)
+			c.server.Store(hello)
+
+			slog.Info(
+				"tcp ctl client handshake finished",
+				slog.String("name", c.name),
+				slog.Int("version", hello.Version),
+				slog.String("build", hello.Build),
+				slog.Any("capabilities", hello.Capabilities),
+				slog.String("framing", hello.Framing.String()),
+				slog.Int("max_size", hello.MaxSize),
+			)
+
+			return nil
+		case MsgResult, MsgAuth:
+			// 旧版服务端将握手消息视为命令或要求认证
+			return ErrLegacyCtlServer
+		default:
+			// 握手完成前的广播消息直接丢弃
+		}
+	}
+}
+
+// redial 按指数退避重新建立连接，客户端释放或认证失败时放弃重连
+func (c *CtlTcpClient) redial() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		c.setConnState(ConnReconnecting)
+	}
+
+	backoff := reconnectBackoffMin
+
+	for {
+		select {
+		case <-c.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		err := c.connect()
+		if err == nil {
+			slog.Info(
+				"tcp ctl client reconnected",
+				slog.String("name", c.name),
+			)
+			return true
+		}
+
+		slog.Warn(
+			"tcp ctl client reconnect failed",
+			slog.Any("error", err),
+			slog.String("name", c.name),
+			slog.Duration("backoff", backoff),
+		)
+
+		if errors.Is(err, ErrAuthFailed) ||
+			errors.Is(err, ErrIncompatibleProtocol) {
+			return false
+		}
+
+		backoff = min(backoff*2, reconnectBackoffMax)
+	}
+}
+
+func (c *CtlTcpClient) readAuth() (*authData, error) {
+	msg, err := c.readMsg()
+	if err != nil {
+		return nil, err
+	}
+
+	if msg.GetType() == MsgResult {
+		if result, err := msg.GetResult(); err == nil && result != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAuthFailed, result.Message)
+		}
+	}
+
+	auth, err := msg.getAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	if auth == nil || auth.Rtn != 0 {
+		return nil, fmt.Errorf("%w: %+v", ErrAuthFailed, auth)
+	}
+
+	return auth, nil
+}
+
+func (c *CtlTcpClient) authenticate(cred *authCredential) error {
+	if cred == nil {
+		return nil
+	}
+
+	c.conn.SetDeadline(time.Now().Add(ctlAuthTimeout))
+	defer c.conn.SetDeadline(time.Time{})
+
+	msg, err := newAuthMessage(&authData{Identity: cred.identity})
+	if err != nil {
+		return err
+	}
+
+	if err = c.writeMsg(msg); err != nil {
+		return err
+	}
+
+	challenge, err := c.readAuth()
+	if err != nil {
+		return err
+	}
+
+	if challenge.Nonce == "" {
+		// 服务端已通过证书或对端进程凭证完成认证
+		if challenge.Identity != "" {
+			slog.Info(
+				"tcp ctl client authenticated by transport",
+				slog.String("identity", challenge.Identity),
+			)
+			return nil
+		}
+
+		return errors.Join(ErrAuthFailed, errors.New("no challenge nonce"))
+	}
+
+	if msg, err = newAuthMessage(&authData{
+		Identity:  cred.identity,
+		Signature: signChallenge(cred.secret, cred.identity, challenge.Nonce),
+	}); err != nil {
+		return err
+	}
+
+	if err = c.writeMsg(msg); err != nil {
+		return err
+	}
+
+	if _, err = c.readAuth(); err != nil {
+		return err
+	}
+
+	slog.Info(
+		"tcp ctl client authenticated",
+		slog.String("identity", cred.identity),
+	)
+
+	return nil
 }
 
 func (c *CtlTcpClient) recv() {
 	defer c.closeLoop()
 
-	rd := bufio.NewScanner(c.conn)
+	for {
+		c.readLoop()
+
+		// 等待中的 Call 不会再收到结果，MessageLoop 订阅在重连期间保持
+		c.cancelCalls()
+
+		if reason := c.closed.Load(); reason != nil {
+			slog.Warn(
+				"tcp ctl client closed by server, skip reconnect",
+				slog.String("name", c.name),
+				slog.String("reason", reason.Reason),
+			)
+			break
+		}
 
-	for rd.Scan() {
-		var msg Message
+		if !c.reconnect || !c.redial() {
+			break
+		}
 
-		if err := json.Unmarshal(rd.Bytes(), &msg); err != nil {
+		if err := c.Command(&Command{Name: "info"}); err != nil {
+			slog.Error(
+				"make reconnected info command failed",
+				slog.Any("error", err),
+			)
+		}
+
+		c.resubscribe(c.send)
+	}
+
+	c.setConnState(ConnClosed)
+
+	slog.Info("tcp client conn closed")
+}
+
+func (c *CtlTcpClient) readLoop() {
+	for {
+		msg, err := c.readMsg()
+		if errors.Is(err, ErrInvalidMsgData) {
 			slog.Error(
 				"unmarshal tcp message failed",
 				slog.Any("error", err),
 			)
 			continue
+		} else if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Error(
+					"read tcp message failed",
+					slog.Any("error", err),
+				)
+			}
+			break
 		}
 
-		if err := c.MemoChannel.Publish(&msg, time.Second*5); err != nil {
+		c.seen.Store(time.Now().UnixNano())
+
+		switch msg.GetType() {
+		case MsgPing:
+			if err := c.writeMsg(newPongMessage(msg)); err != nil {
+				slog.Error(
+					"write pong msg failed",
+					slog.Any("error", err),
+				)
+			}
+			continue
+		case MsgPong:
+			if rtt, err := msg.getRTT(); err != nil {
+				slog.Error(
+					"get heartbeat rtt failed",
+					slog.Any("error", err),
+				)
+			} else {
+				c.rtt.Store(int64(rtt))
+			}
+			continue
+		case MsgClose:
+			// 服务端随后关闭连接，读取结束后不再重连
+			reason, err := msg.getClose()
+			if err != nil || reason == nil {
+				reason = &closeData{Reason: "closed by server"}
+			}
+			c.closed.Store(reason)
+			continue
+		}
+
+		// Call 的结果由调用方处理，不再重复投递
+		if c.resolveCall(msg) {
+			continue
+		}
+
+		if err := c.MemoChannel.Publish(msg, time.Second*5); err != nil {
 			slog.Error(
 				"publish message failed",
 				slog.Any("error", err),
@@ -38,13 +436,63 @@ func (c *CtlTcpClient) recv() {
 			)
 		}
 	}
+}
 
-	slog.Info("tcp client conn closed")
+// keepalive 周期性发送 Ping，超时未收到服务端消息时关闭连接以触发重连
+func (c *CtlTcpClient) keepalive() {
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+
+	timeout := c.heartbeat * heartbeatTimeoutFactor
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		if c.GetConnState() != ConnConnected {
+			continue
+		}
+
+		if idle := time.Since(time.Unix(0, c.seen.Load())); idle > timeout {
+			slog.Warn(
+				"ctl server heartbeat timeout",
+				slog.String("name", c.name),
+				slog.Duration("idle", idle),
+			)
+
+			c.closeConn()
+			continue
+		}
+
+		ping, err := newPingMessage()
+		if err != nil {
+			slog.Error(
+				"create ping message failed",
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		if err = c.send(ping); err != nil {
+			slog.Error(
+				"send ping msg failed",
+				slog.Any("error", err),
+				slog.String("name", c.name),
+			)
+		}
+	}
 }
 
 func (c *CtlTcpClient) Start() {
 	go c.recv()
 
+	if c.heartbeat > 0 {
+		go c.keepalive()
+	}
+
 	if err := c.Command(&Command{
 		Name: "info",
 	}); err != nil {
@@ -59,39 +507,107 @@ func (c *CtlTcpClient) Command(cmd *Command) error {
 	if err != nil {
 		return err
 	}
-	data, err := json.Marshal(msg)
-	if err != nil {
-		return err
-	}
 
-	if _, err = c.conn.Write(append(data, '\n')); err != nil {
-		c.closeLoop()
-	}
+	return c.send(msg)
+}
 
-	return err
+func (c *CtlTcpClient) Call(ctx context.Context, cmd *Command) (*Result, error) {
+	return c.call(ctx, cmd, c.send)
 }
 
 func (c *CtlTcpClient) Release() {
-	c.conn.Close()
+	c.closeOnce.Do(func() { close(c.done) })
+	c.closeConn()
 
 	c.ctlBaseClient.Release()
 }
 
+func newCtlTcpClient(
+	name string, dial ctlDialer, cred *authCredential, opts url.Values,
+) (*CtlTcpClient, error) {
+	client := CtlTcpClient{
+		ctlBaseClient: ctlBaseClient{
+			name: name,
+		},
+		dial:      dial,
+		cred:      cred,
+		reconnect: true,
+		framing:   framingLength,
+		done:      make(chan struct{}),
+	}
+
+	if v := opts.Get("reconnect"); v != "" {
+		var err error
+		if client.reconnect, err = strconv.ParseBool(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := opts.Get("heartbeat"); v != "" {
+		var err error
+		if client.heartbeat, err = time.ParseDuration(v); err != nil {
+			return nil, err
+		}
+	}
+
+	if v := opts.Get("framing"); v != "" {
+		if err := client.framing.UnmarshalText([]byte(v)); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if client.maxSize, err = parseMaxMsgSize(opts.Get("maxsize")); err != nil {
+		return nil, err
+	}
+
+	if err := client.connect(); err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// NewCtlTcpClient 连接字串格式为：[{identity}:{secret}@]{ip}:{port}[?reconnect=false&heartbeat=10s]
+// 连接字串中未包含认证信息时，尝试从环境变量 LATENCY_CTL_AUTH 中获取
 func NewCtlTcpClient(conn string) (*CtlTcpClient, error) {
-	dialer := net.Dialer{
-		Timeout: time.Second * 10,
+	conn, opts, err := splitConnOptions(conn)
+	if err != nil {
+		return nil, err
 	}
-	c, err := dialer.Dial("tcp4", conn)
+
+	addr, cred := parseAuthConn(conn)
+
+	return newCtlTcpClient(addr, tcpDialer(addr, nil), cred, opts)
+}
+
+// NewCtlTlsClient 连接字串格式为：[{identity}:{secret}@]{ip}:{port}[?{options}]
+// 可用参数：ca 服务端 CA；cert, key 客户端证书(mTLS)；name 服务端证书名称；reconnect 断线重连；heartbeat 心跳周期
+func NewCtlTlsClient(conn string) (*CtlTcpClient, error) {
+	conn, opts, err := splitConnOptions(conn)
 	if err != nil {
 		return nil, err
 	}
 
-	client := CtlTcpClient{
-		ctlBaseClient: ctlBaseClient{
-			name: conn,
-		},
-		conn: c,
+	addr, cred := parseAuthConn(conn)
+
+	tlsCfg, err := newClientTlsConfig(addr, opts)
+	if err != nil {
+		return nil, err
 	}
 
-	return &client, nil
+	return newCtlTcpClient(addr, tcpDialer(addr, tlsCfg), cred, opts)
+}
+
+// NewCtlUnixClient 连接字串格式为：[{identity}:{secret}@]{sock_path}[?reconnect=false&heartbeat=10s]
+// 服务端以对端进程凭证认证时，无需指定认证信息
+func NewCtlUnixClient(conn string) (*CtlTcpClient, error) {
+	conn, opts, err := splitConnOptions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	path, cred := parseAuthConn(conn)
+
+	return newCtlTcpClient(path, unixDialer(path), cred, opts)
 }
@@ -1,6 +1,14 @@
 package ctl
 
-import "testing"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestSSHConn(t *testing.T) {
 	conn := "abc:test@127.0.0.1?127.0.0.1:45678"
@@ -9,3 +17,264 @@ func TestSSHConn(t *testing.T) {
 
 	t.Log(match)
 }
+
+func TestClientCall(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	// 逆序返回同名命令的结果，校验结果按 msgID 匹配
+	go func() {
+		var cmds []*Message
+
+		for msg := range hdl.Commands() {
+			if cmds = append(cmds, msg); len(cmds) < 2 {
+				continue
+			}
+
+			for idx := len(cmds) - 1; idx >= 0; idx-- {
+				cmd, _ := cmds[idx].GetCommand()
+				data, _ := json.Marshal(&Result{
+					CmdName: cmd.Name,
+					Message: cmd.KwArgs["seq"],
+				})
+
+				hdl.Publish(&Message{
+					msgID:   cmds[idx].msgID,
+					msgType: MsgResult,
+					data:    data,
+				}, time.Second)
+			}
+			cmds = nil
+		}
+	}()
+
+	client, err := NewCtlTcpClient(hdl.listen.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	loopResults := make(chan *Result, 10)
+	if err := client.MessageLoop(
+		"test loop", nil, nil,
+		func(r *Result) error { loopResults <- r; return nil },
+		nil,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for _, seq := range []string{"1", "2"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := client.Call(t.Context(), &Command{
+				Name: "state", KwArgs: map[string]string{"seq": seq},
+			})
+			if err != nil {
+				t.Error(err)
+			} else if result.Message != seq {
+				t.Errorf("result mismatch: expect %s, got %s", seq, result.Message)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Call 的结果不再投递至 MessageLoop，消息循环仅收到无等待方的结果
+	for range 2 {
+		if err := client.Command(&Command{Name: "info"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case r := <-loopResults:
+		if r.CmdName != "info" {
+			t.Fatalf("call result delivered to message loop: %+v", r)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait message loop result timeout")
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*100)
+	defer cancel()
+
+	if _, err = client.Call(ctx, &Command{Name: "state"}); !errors.Is(
+		err, context.DeadlineExceeded,
+	) {
+		t.Fatalf("call not timeout: %+v", err)
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	go func() {
+		for msg := range hdl.Commands() {
+			cmd, _ := msg.GetCommand()
+			data, _ := json.Marshal(&Result{CmdName: cmd.Name})
+
+			hdl.Publish(&Message{
+				msgID:   msg.msgID,
+				msgType: MsgResult,
+				data:    data,
+			}, time.Second)
+		}
+	}()
+
+	client, err := NewCtlTcpClient(hdl.listen.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	states := make(chan ConnState, 10)
+	client.WatchConnState("test", func(s ConnState) { states <- s })
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	// 服务端主动断开全部连接
+	hdl.hdlConnections.Range(func(_, value any) bool {
+		value.(*tcpMsgWriter).conn.Close()
+		return true
+	})
+
+	for _, expect := range []ConnState{ConnReconnecting, ConnConnected} {
+		select {
+		case state := <-states:
+			if state != expect {
+				t.Fatalf("conn state mismatch: expect %s, got %s", expect, state)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("wait conn state %s timeout", expect)
+		}
+	}
+
+	if _, err = client.Call(t.Context(), &Command{Name: "state"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0?heartbeat=50ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	addr := hdl.listen.Addr().String()
+
+	client, err := NewCtlTcpClient(addr + "?heartbeat=50ms")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() {
+		go client.recv()
+		go client.keepalive()
+	})
+	defer client.Release()
+
+	// 不响应心跳的连接应被服务端移除
+	dead, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dead.Close()
+
+	deadline := time.After(time.Second * 5)
+	for hdl.ConnCount() != 2 {
+		select {
+		case <-deadline:
+			t.Fatalf("wait connections timeout: %d", hdl.ConnCount())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	for hdl.ConnCount() != 1 || client.GetRTT() <= 0 {
+		select {
+		case <-deadline:
+			t.Fatalf(
+				"dead connection not evicted: %d, rtt: %s",
+				hdl.ConnCount(), client.GetRTT(),
+			)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if state := client.GetConnState(); state != ConnConnected {
+		t.Fatalf("client conn state mismatch: %s", state)
+	}
+}
+
+func TestClientRouting(t *testing.T) {
+	addr := "[::1]:0"
+	if listen, err := net.Listen("tcp", addr); err != nil {
+		addr = "127.0.0.1:0"
+	} else {
+		listen.Close()
+	}
+
+	hdl, err := NewCtlTcpHandler(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	go func() {
+		for msg := range hdl.Commands() {
+			cmd, _ := msg.GetCommand()
+			data, _ := json.Marshal(&Result{
+				CmdName: cmd.Name,
+				Message: cmd.KwArgs["client"],
+			})
+
+			hdl.Publish(&Message{
+				msgID:   msg.msgID,
+				msgType: MsgResult,
+				data:    data,
+			}, time.Second)
+		}
+	}()
+
+	// 各客户端的消息ID 均从 1 开始，且来自相同的远端地址
+	wg := sync.WaitGroup{}
+	for _, name := range []string{"a", "b", "c"} {
+		client, err := NewCtlTcpClient(hdl.listen.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Init(t.Context(), "test client", func() { go client.recv() })
+		defer client.Release()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 10 {
+				result, err := client.Call(t.Context(), &Command{
+					Name: "state", KwArgs: map[string]string{"client": name},
+				})
+				if err != nil {
+					t.Error(err)
+					return
+				} else if result.Message != name {
+					t.Errorf("result routed to wrong client: expect %s, got %s", name, result.Message)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -1,8 +1,11 @@
 package ctl
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/frozenpine/latency4go"
@@ -12,9 +15,31 @@ import (
 type Command struct {
 	Name   string
 	KwArgs map[string]string
+
+	// 命令来源会话，由 CtlServer 执行前附加
+	session *session
+	// 命令执行进度的通知函数，由 CtlServer 执行前附加
+	progress func(stage, message string)
+	// 命令执行时的延迟客户端，由 Execute 加载一次，
+	// 避免并发命令执行期间 stop 清空客户端
+	client *latency4go.LatencyClient
+}
+
+// Client 命令执行时的延迟客户端，ClientFree 命令在客户端未运行时为 nil
+func (cmd *Command) Client() *latency4go.LatencyClient {
+	return cmd.client
 }
 
-func (cmd *Command) Execute(svr *CtlServer) (result *Result, err error) {
+// Report 通知命令执行进度，非 CtlServer 调度执行时忽略
+func (cmd *Command) Report(stage, message string) {
+	if cmd.progress != nil {
+		cmd.progress(stage, message)
+	}
+}
+
+func (cmd *Command) Execute(
+	ctx context.Context, svr *CtlServer,
+) (result *Result, err error) {
 	slog.Info(
 		"executing command",
 		slog.Any("cmd", cmd),
@@ -25,243 +50,735 @@ func (cmd *Command) Execute(svr *CtlServer) (result *Result, err error) {
 		Values:  make(values),
 	}
 
-	client := svr.instance.Load()
-	if client == nil && cmd.Name != "start" {
+	spec, exist := GetCommandSpec(cmd.Name)
+	if !exist {
+		result.Rtn = 1
+		result.Message = ErrUnknownCommand.Error()
+		return
+	}
+
+	cmd.client = svr.instance.Load()
+	if cmd.client == nil && !spec.ClientFree {
 		result.Rtn = 1
 		result.Message = "no latency client running"
 		return
 	}
 
-	switch cmd.Name {
-	case "suspend":
-		if client.Suspend() {
-			result.Message = "suspend success"
-		} else {
-			result.Rtn = 1
-			result.Message = "suspend failed"
-		}
-	case "resume":
-		if client.Resume() {
-			result.Message = "resume success"
-		} else {
-			result.Rtn = 1
-			result.Message = "resume failed"
-		}
-	case "stop":
-		if err = svr.StopLatencyClient(); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"stop latency client failed: %+v", err,
-			)
-		} else {
-			result.Message = "latency client stopped"
-		}
-	case "start":
-		var newClient *latency4go.LatencyClient
-		if newClient, err = svr.StartLatencyClient(cmd.KwArgs); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"start latency client failed: %+v", err,
-			)
-		} else {
-			interval := newClient.GetInterval()
-
-			plugins := []*libs.PluginContainer{}
-
-			libs.RangePlugins(func(name string, container *libs.PluginContainer) error {
-				plugins = append(plugins, container)
-				return nil
-			})
-
-			result.Values[VKeyHandler] = latency4go.ConvertSlice(
-				svr.handlers,
-				func(h Handler) string {
-					return h.ConnName()
-				},
-			)
-			result.Values[VKeyInterval] = interval
-			result.Values[VKeyPlugin] = plugins
-			result.Message = "latency client started"
-		}
-	case "period":
-		intv, err := time.ParseDuration(cmd.KwArgs["interval"])
-		if err != nil {
-			result.Rtn = 1
+	if err = spec.Validate(cmd.KwArgs); err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return
+	}
+
+	if err = spec.Handler(ctx, svr, cmd, result); err != nil && result.Rtn == 0 {
+		result.Rtn = 1
+		if result.Message == "" {
 			result.Message = err.Error()
-			break
 		}
+	}
 
-		rtn := client.ChangeInterval(intv)
-		if rtn <= 0 {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"%+v: invalid interval", ErrInvalidMsgData,
-			)
-		}
+	return
+}
 
-		result.Values[VKeyIntervalOrigin] = rtn
-		result.Values[VKeyInterval] = intv
-		result.Message = "interval changed"
-	case "state":
-		if state := client.GetLastState(); state != nil {
-			result.Values[VKeyState] = state
-			result.Message = "get last state succeded"
-		} else {
-			result.Rtn = 1
-			result.Message = "get last state failed"
-		}
-	case "config":
-		if err = client.SetConfig(cmd.KwArgs); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"%+v: set config failed", err,
-			)
-			return
-		}
+var queryConfigArgs = []ArgSpec{
+	{Name: "config", Type: ArgJSON, Help: "complete query config"},
+	{Name: "before", Type: ArgString, Help: "query time range before now"},
+	{Name: "range", Type: ArgString, Help: "query time range: from=...[,to=...]"},
+	{Name: "from", Type: ArgInt, Help: "tick2order from in pico sec"},
+	{Name: "to", Type: ArgInt, Help: "tick2order to in pico sec"},
+	{Name: "agg", Type: ArgInt, Help: "aggregation result count"},
+	{Name: "least", Type: ArgInt, Help: "at least doc count for aggregation"},
+	{Name: "sort", Type: ArgString, Help: "sort fronts by elastic painless"},
+	{Name: "user", Type: ArgString, Help: "client id filter, comma separated"},
+	{Name: "percents", Type: ArgString, Help: "quantiles, comma separated"},
+}
 
-		if cfg := client.GetConfig(); cfg != nil {
-			result.Values[VKeyConfig] = cfg
-			result.Message = "config set succeded"
-		} else {
-			result.Rtn = 1
-			result.Message = "config setted, but no data return"
-		}
-	case "query":
-		var state *latency4go.State
-		state, err = client.QueryLatency(cmd.KwArgs)
-		if err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"%+v: query latency failed", err,
-			)
+var topicNames = []string{
+	string(TopicState), string(TopicTopK), string(TopicPlugin),
+	string(TopicAlert), string(TopicLog), string(TopicProposal),
+}
 
-			return
+func init() {
+	for _, spec := range []CommandSpec{
+		{
+			Name: "start", Help: "start latency client", Role: RoleAdmin,
+			ClientFree: true, Handler: cmdStart,
+			Args: []ArgSpec{
+				{Name: "schema", Type: ArgString, Enum: []string{"http", "https"}, Help: "elasticsearch schema"},
+				{Name: "host", Type: ArgString, Help: "elasticsearch host"},
+				{Name: "port", Type: ArgUint, Help: "elasticsearch port"},
+				{Name: "sink", Type: ArgString, Help: "latency result sink file"},
+				{Name: "interval", Type: ArgDuration, Help: "query interval"},
+			},
+		},
+		{
+			Name: "stop", Help: "stop latency client", Role: RoleAdmin,
+			Handler: cmdStop,
+		},
+		{
+			Name: "suspend", Help: "suspend latency client running",
+			Role: RoleOperator, Handler: cmdSuspend,
+		},
+		{
+			Name: "resume", Help: "resume suspended latency client",
+			Role: RoleOperator, Handler: cmdResume,
+		},
+		{
+			Name: "period", Help: "change latency client query period",
+			Role: RoleOperator, Handler: cmdPeriod,
+			Args: []ArgSpec{
+				{Name: "interval", Type: ArgDuration, Required: true, Positional: true, Help: "query interval"},
+			},
+		},
+		{
+			Name: "state", Help: "get latency client last state",
+			Role: RoleViewer, Concurrent: true, Handler: cmdState,
+		},
+		{
+			Name: "config", Help: "change latency client query config",
+			Role: RoleOperator, Handler: cmdConfig, Args: queryConfigArgs,
+		},
+		{
+			Name: "query", Help: "query latency result with onetime config",
+			Role: RoleViewer, Concurrent: true, Handler: cmdQuery,
+			Args: queryConfigArgs,
+		},
+		{
+			Name: "plugin", Help: "add latency reporter plugin",
+			Role: RoleAdmin, Handler: cmdPlugin,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Required: true, Help: "plugin name"},
+				{Name: "config", Type: ArgString, Required: true, Help: "plugin config"},
+				{Name: "lib", Type: ArgString, Required: true, Help: "plugin lib dir"},
+			},
+		},
+		{
+			Name: "unplugin", Help: "remove reporter plugin from latency client",
+			Role: RoleAdmin, Handler: cmdUnplugin,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Required: true, Positional: true, Help: "plugin name"},
+			},
+		},
+		{
+			Name: "info", Help: "get latency client info",
+			Role: RoleViewer, Concurrent: true, Handler: cmdInfo,
+		},
+		{
+			Name: "seats", Help: "list trading system seats of plugin",
+			Role: RoleViewer, ClientFree: true, Handler: cmdSeats,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Required: true, Positional: true, Help: "plugin name"},
+			},
+		},
+		{
+			Name: "priority", Help: "get trading system priority levels of plugin",
+			Role: RoleViewer, ClientFree: true, Handler: cmdPriority,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Required: true, Positional: true, Help: "plugin name"},
+			},
+		},
+		{
+			Name: "push", Help: "push fronts priority to plugins bypassing query",
+			Role: RoleOperator, Handler: cmdPush,
+			Args: []ArgSpec{
+				{Name: "addrs", Type: ArgString, Help: "front addresses in priority order, comma separated"},
+				{Name: "order", Type: ArgString, Help: "reorder current state by ranks from 1, comma separated"},
+				{Name: "plugin", Type: ArgString, Help: "target plugins, comma separated, default all"},
+				{Name: "until", Type: ArgString, Help: "pin plugins until time(RFC3339) or duration"},
+			},
+		},
+		{
+			Name: "unpin", Help: "unpin plugins to resume periodic report",
+			Role: RoleOperator, Handler: cmdUnpin,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Positional: true, Help: "target plugins, comma separated, default all"},
+			},
+		},
+		{
+			Name: "history", Help: "list recent reported states of plugins",
+			Role: RoleViewer, Concurrent: true, Handler: cmdHistory,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Positional: true, Help: "target plugins, comma separated, default all"},
+			},
+		},
+		{
+			Name: "rollback", Help: "re-push previous reported state to plugins",
+			Role: RoleOperator, Handler: cmdRollback,
+			Args: []ArgSpec{
+				{Name: "steps", Type: ArgUint, Positional: true, Help: "steps back from last reported state, default 1"},
+				{Name: "plugin", Type: ArgString, Help: "target plugins, comma separated, default all"},
+				{Name: "hold", Type: ArgDuration, Help: "suspend periodic report after rollback"},
+			},
+		},
+		{
+			Name: "approval", Help: "set plugin approval mode for priority changes",
+			Role: RoleAdmin, Handler: cmdApproval,
+			Args: []ArgSpec{
+				{Name: "plugin", Type: ArgString, Required: true, Positional: true, Help: "plugin name"},
+				{Name: "ttl", Type: ArgDuration, Help: "proposal expire duration, default 5m"},
+				{Name: "off", Type: ArgBool, Help: "turn off approval mode"},
+			},
+		},
+		{
+			Name: "proposals", Help: "list pending priority proposals",
+			Role: RoleViewer, Concurrent: true, Handler: cmdProposals,
+		},
+		{
+			Name: "approve", Help: "approve pending priority proposal",
+			Role: RoleOperator, Handler: cmdApprove,
+			Args: []ArgSpec{
+				{Name: "id", Type: ArgUint, Required: true, Positional: true, Help: "proposal id"},
+			},
+		},
+		{
+			Name: "reject", Help: "reject pending priority proposal",
+			Role: RoleOperator, Handler: cmdApprove,
+			Args: []ArgSpec{
+				{Name: "id", Type: ArgUint, Required: true, Positional: true, Help: "proposal id"},
+			},
+		},
+		{
+			Name: "audit", Help: "list recent ctl command audit entries",
+			Role: RoleOperator, ClientFree: true, Concurrent: true,
+			Handler: cmdAudit,
+			Args: []ArgSpec{
+				{Name: "count", Type: ArgUint, Positional: true, Help: "entry count, default 20, max 1000"},
+				{Name: "identity", Type: ArgString, Help: "filter by identity"},
+				{Name: "name", Type: ArgString, Help: "filter by command name"},
+			},
+		},
+		{
+			Name: "subscribe", Help: "subscribe broadcast topic",
+			Role: RoleViewer, ClientFree: true, Concurrent: true,
+			Handler: cmdSubscribe,
+			Args: []ArgSpec{
+				{Name: "topic", Type: ArgString, Required: true, Positional: true, Enum: topicNames, Help: "broadcast topic"},
+				{Name: "k", Type: ArgUint, Help: "top k fronts for topk topic"},
+				{Name: "changed", Type: ArgBool, Help: "notify only when topk changed"},
+				{Name: "level", Type: ArgString, Help: "min server log level for log topic"},
+			},
+		},
+		{
+			Name: "unsubscribe", Help: "unsubscribe broadcast topic",
+			Role: RoleViewer, ClientFree: true, Concurrent: true,
+			Handler: cmdSubscribe,
+			Args: []ArgSpec{
+				{Name: "topic", Type: ArgString, Required: true, Positional: true, Enum: topicNames, Help: "broadcast topic"},
+			},
+		},
+		{
+			Name: "sessions", Help: "list connected ctl sessions",
+			Role: RoleAdmin, ClientFree: true, Concurrent: true,
+			Handler: cmdSessions,
+		},
+		{
+			Name: "kick", Help: "disconnect ctl session",
+			Role: RoleAdmin, ClientFree: true, Concurrent: true,
+			Handler: cmdKick,
+			Args: []ArgSpec{
+				{Name: "remote", Type: ArgString, Required: true, Positional: true, Help: "session remote address"},
+				{Name: "handler", Type: ArgString, Positional: true, Help: "session handler"},
+			},
+		},
+		{
+			Name: "cancel", Help: "cancel in-flight command",
+			Role: RoleViewer, ClientFree: true, Concurrent: true,
+			Handler: cmdCancel,
+			Args: []ArgSpec{
+				{Name: "id", Type: ArgUint, Required: true, Positional: true, Help: "command msg id"},
+			},
+		},
+		{
+			Name: "schema", Help: "list command schema",
+			Role: RoleViewer, ClientFree: true, Concurrent: true,
+			Handler: cmdSchema,
+			Args: []ArgSpec{
+				{Name: "name", Type: ArgString, Positional: true, Help: "command name"},
+			},
+		},
+	} {
+		if err := registerCommand(spec); err != nil {
+			panic(err)
 		}
+	}
+}
 
-		if state != nil {
-			result.Values[VKeyState] = state
-			result.Message = "latency queried"
-		} else {
-			result.Rtn = 1
-			result.Message = "latency query finished, but no state return"
-		}
-	case "plugin":
-		name, exist := cmd.KwArgs["plugin"]
-		if !exist {
-			result.Rtn = 1
-			result.Message = "no plugin name"
-			err = fmt.Errorf("%w: no plugin name", ErrInvalidMsgData)
-			return
-		}
+func cmdSuspend(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	if cmd.client.Suspend() {
+		result.Message = "suspend success"
+	} else {
+		result.Rtn = 1
+		result.Message = "suspend failed"
+	}
 
-		config, exist := cmd.KwArgs["config"]
-		if !exist {
-			result.Rtn = 1
-			result.Message = "no plugin config"
-			err = fmt.Errorf("%w: no plugin config", ErrInvalidMsgData)
-			return
-		}
-		libDir, exist := cmd.KwArgs["lib"]
-		if !exist {
-			result.Rtn = 1
-			result.Message = "no plugin base dir"
-			err = fmt.Errorf("%w: no plugin lib dir", ErrInvalidMsgData)
-			return
-		}
+	return nil
+}
 
-		var container *libs.PluginContainer
-		if container, err = libs.NewPlugin(libDir, name); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"create plugin failed: %+v", err,
-			)
-			return
-		}
+func cmdResume(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	if cmd.client.Resume() {
+		result.Message = "resume success"
+	} else {
+		result.Rtn = 1
+		result.Message = "resume failed"
+	}
 
-		if err = container.Init(svr.ctx, config); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"init plugin failed: %+v", err,
-			)
-			return
-		}
+	return nil
+}
 
-		if err = client.AddReporter(
-			name, func(s *latency4go.State) error {
-				return container.ReportFronts(s.AddrList...)
-			},
-		); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"add reporter failed: %+v", err,
-			)
-			return
-		}
+func cmdStop(_ context.Context, svr *CtlServer, _ *Command, result *Result) error {
+	if err := svr.StopLatencyClient(); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"stop latency client failed: %+v", err,
+		)
+		return err
+	}
 
-		result.Message = "new plugin added"
-	case "unplugin":
-		name, exist := cmd.KwArgs["plugin"]
-		if !exist {
-			result.Rtn = 1
-			result.Message = "no plugin name"
-			err = fmt.Errorf("%w: no plugin name", ErrInvalidMsgData)
-			return
-		}
+	result.Message = "latency client stopped"
+
+	return nil
+}
+
+func cmdStart(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	cmd.Report("starting", "connecting to elasticsearch")
+
+	newClient, err := svr.StartLatencyClient(cmd.KwArgs)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"start latency client failed: %+v", err,
+		)
+		return err
+	}
+
+	interval := newClient.GetInterval()
+
+	plugins := []*libs.PluginContainer{}
+
+	libs.RangePlugins(func(name string, container *libs.PluginContainer) error {
+		plugins = append(plugins, container)
+		return nil
+	})
+
+	result.Values[VKeyHandler] = latency4go.ConvertSlice(
+		svr.handlers,
+		func(h Handler) string {
+			return h.ConnName()
+		},
+	)
+	result.Values[VKeyInterval] = interval
+	result.Values[VKeyPlugin] = plugins
+	result.Message = "latency client started"
+
+	return nil
+}
+
+func cmdPeriod(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	intv, err := time.ParseDuration(cmd.KwArgs["interval"])
+	if err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return err
+	}
+
+	rtn := cmd.client.ChangeInterval(intv)
+	if rtn <= 0 {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"%+v: invalid interval", ErrInvalidMsgData,
+		)
+		return nil
+	}
+
+	result.Values[VKeyIntervalOrigin] = rtn
+	result.Values[VKeyInterval] = intv
+	result.Message = "interval changed"
+
+	return nil
+}
+
+func cmdState(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	if state := cmd.client.GetLastState(); state != nil {
+		result.Values[VKeyState] = state
+		result.Message = "get last state succeded"
+	} else {
+		result.Rtn = 1
+		result.Message = "get last state failed"
+	}
+
+	return nil
+}
+
+func cmdConfig(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+
+	if err := client.SetConfig(cmd.KwArgs); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"%+v: set config failed", err,
+		)
+		return err
+	}
+
+	if cfg := client.GetConfig(); cfg != nil {
+		result.Values[VKeyConfig] = cfg
+		result.Message = "config set succeded"
+	} else {
+		result.Rtn = 1
+		result.Message = "config setted, but no data return"
+	}
+
+	return nil
+}
+
+func cmdQuery(ctx context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	cmd.Report("querying", "querying latency from elasticsearch")
+
+	state, err := cmd.client.QueryLatencyContext(ctx, cmd.KwArgs)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"%+v: query latency failed", err,
+		)
+		return err
+	}
+
+	if state != nil {
+		result.Values[VKeyState] = state
+		result.Message = "latency queried"
+	} else {
+		result.Rtn = 1
+		result.Message = "latency query finished, but no state return"
+	}
+
+	return nil
+}
+
+func cmdPlugin(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	name, config, libDir := cmd.KwArgs["plugin"], cmd.KwArgs["config"], cmd.KwArgs["lib"]
+
+	cmd.Report("loading", fmt.Sprintf("loading plugin %s from %s", name, libDir))
+
+	container, err := libs.NewPlugin(libDir, name)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"create plugin failed: %+v", err,
+		)
+		return err
+	}
+
+	cmd.Report("initializing", fmt.Sprintf("initializing plugin %s", name))
+	if err = container.Init(svr.ctx, config); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"init plugin failed: %+v", err,
+		)
+		return err
+	}
+
+	if err = cmd.client.AddReporter(
+		name, func(s *latency4go.State) error {
+			if err := container.ReportFronts(s.AddrList...); err != nil {
+				svr.alert(
+					slog.LevelError, name,
+					fmt.Sprintf("report fronts failed: %+v", err),
+				)
+				return err
+			}
+
+			return nil
+		},
+	); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"add reporter failed: %+v", err,
+		)
+		return err
+	}
+
+	svr.applyApprovals(cmd.client, name)
+
+	result.Message = "new plugin added"
+	svr.publishEvent(TopicPlugin, &PluginEvent{
+		Name:    name,
+		Action:  "load",
+		Message: result.Message,
+	})
+
+	return nil
+}
+
+func cmdUnplugin(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	name := cmd.KwArgs["plugin"]
+
+	if err := cmd.client.DelReporter(name); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"del reporter from client faield: %+v", err,
+		)
+		return err
+	}
+
+	container, err := libs.GetAndUnRegisterPlugin(name)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"get registered plugin failed: %+v", err,
+		)
+		return err
+	}
+
+	container.Stop()
+	if err = container.Join(); err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"%+v: plugin stop failed", err,
+		)
+	} else {
+		result.Message = "plugin unloaded"
+	}
+
+	svr.publishEvent(TopicPlugin, &PluginEvent{
+		Name:    name,
+		Action:  "unload",
+		Message: result.Message,
+	})
+
+	return err
+}
+
+func cmdInfo(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+
+	if state := client.GetLastState(); state != nil {
+		result.Values[VKeyState] = state
+	}
+
+	interval := client.GetInterval()
+
+	plugins := []*libs.PluginContainer{}
+
+	libs.RangePlugins(func(name string, container *libs.PluginContainer) error {
+		plugins = append(plugins, container)
+		return nil
+	})
+
+	result.Values[VKeyHandler] = latency4go.ConvertSlice(
+		svr.handlers,
+		func(h Handler) string {
+			return h.ConnName()
+		},
+	)
+	result.Values[VKeyInterval] = interval
+	result.Values[VKeyPlugin] = plugins
+
+	queues := []QueueStat{}
+	for _, hdl := range svr.handlers {
+		queues = append(queues, hdl.QueueStats()...)
+	}
+	result.Values[VKeyQueue] = queues
+
+	result.Values[VKeyPin] = client.GetPins()
+
+	result.Message = "get info finished"
+
+	return nil
+}
+
+func cmdSeats(_ context.Context, _ *CtlServer, cmd *Command, result *Result) error {
+	name := cmd.KwArgs["plugin"]
+
+	container, err := libs.GetRegisteredPlugin(name)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"get registered plugin failed: %+v", err,
+		)
+		return err
+	}
+
+	seats, err := container.GetSeats()
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf("get seats failed: %+v", err)
+		return err
+	}
+
+	result.Values[VKeyPluginName] = name
+	result.Values[VKeySeat] = seats
+	result.Message = "get seats finished"
+
+	return nil
+}
+
+func cmdPriority(_ context.Context, _ *CtlServer, cmd *Command, result *Result) error {
+	name := cmd.KwArgs["plugin"]
+
+	container, err := libs.GetRegisteredPlugin(name)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"get registered plugin failed: %+v", err,
+		)
+		return err
+	}
+
+	priority, err := container.GetPriority()
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf("get priority failed: %+v", err)
+		return err
+	}
 
-		if err = client.DelReporter(name); err != nil {
+	result.Values[VKeyPluginName] = name
+	result.Values[VKeyPriority] = priority
+	result.Message = "get priority finished"
+
+	return nil
+}
+
+func cmdAudit(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	if svr.auditor == nil {
+		result.Rtn = 1
+		result.Message = ErrAuditDisabled.Error()
+		return nil
+	}
+
+	count := defaultAuditCount
+	if v, exist := cmd.KwArgs["count"]; exist {
+		var err error
+		if count, err = strconv.Atoi(v); err != nil ||
+			count <= 0 || count > maxAuditCount {
 			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"del reporter from client faield: %+v", err,
-			)
-			return
+			result.Message = fmt.Sprintf("invalid audit count: %s", v)
+			return fmt.Errorf("%w: invalid audit count", ErrInvalidMsgData)
 		}
+	}
+
+	identity, name := cmd.KwArgs["identity"], cmd.KwArgs["name"]
 
-		var container *libs.PluginContainer
-		container, err = libs.GetAndUnRegisterPlugin(name)
+	entries, err := svr.auditor.recent(
+		count, func(entry *AuditEntry) bool {
+			return (identity == "" || entry.Identity == identity) &&
+				(name == "" || entry.Command == name)
+		},
+	)
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"read audit log failed: %+v", err,
+		)
+		return err
+	}
+
+	result.Values[VKeyAudit] = entries
+	result.Message = fmt.Sprintf("%d audit entries found", len(entries))
+
+	return nil
+}
+
+func cmdSubscribe(_ context.Context, _ *CtlServer, cmd *Command, result *Result) error {
+	if cmd.session == nil || cmd.session.subs == nil {
+		result.Rtn = 1
+		result.Message = "subscription not supported by connection"
+		return nil
+	}
+
+	topic, err := ParseTopic(cmd.KwArgs["topic"])
+	if err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return err
+	}
+
+	if cmd.Name == "unsubscribe" {
+		cmd.session.subs.del(topic)
+		result.Message = fmt.Sprintf("topic %s unsubscribed", topic)
+	} else {
+		opt, err := parseSubOption(topic, cmd.KwArgs)
 		if err != nil {
 			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"get registered plugin failed: %+v", err,
-			)
-			return
+			result.Message = err.Error()
+			return err
 		}
 
-		container.Stop()
-		if err = container.Join(); err != nil {
-			result.Rtn = 1
-			result.Message = fmt.Sprintf(
-				"%+v: plugin stop failed", err,
-			)
-		} else {
-			result.Message = "plugin unloaded"
+		cmd.session.subs.set(topic, opt)
+		result.Message = fmt.Sprintf("topic %s subscribed", topic)
+	}
+
+	result.Values[VKeySubscription] = cmd.session.subs.list()
+
+	return nil
+}
+
+func cmdSessions(_ context.Context, svr *CtlServer, _ *Command, result *Result) error {
+	sessions := []SessionInfo{}
+	for _, hdl := range svr.handlers {
+		sessions = append(sessions, hdl.Sessions()...)
+	}
+
+	result.Values[VKeySession] = sessions
+	result.Message = fmt.Sprintf("%d sessions connected", len(sessions))
+
+	return nil
+}
+
+func cmdKick(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	remote, name := cmd.KwArgs["remote"], cmd.KwArgs["handler"]
+
+	err := fmt.Errorf("%w: %s", ErrSessionNotFound, remote)
+	for _, hdl := range svr.handlers {
+		if name != "" && hdl.ConnName() != name && hdl.Name() != name {
+			continue
 		}
-	case "info":
-		if state := client.GetLastState(); state != nil {
-			result.Values[VKeyState] = state
+
+		if err = hdl.Kick(remote); !errors.Is(err, ErrSessionNotFound) {
+			break
 		}
+	}
 
-		interval := client.GetInterval()
+	if err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return err
+	}
 
-		plugins := []*libs.PluginContainer{}
+	result.Message = fmt.Sprintf("session %s kicked", remote)
 
-		libs.RangePlugins(func(name string, container *libs.PluginContainer) error {
-			plugins = append(plugins, container)
-			return nil
-		})
+	return nil
+}
 
-		result.Values[VKeyHandler] = latency4go.ConvertSlice(
-			svr.handlers,
-			func(h Handler) string {
-				return h.ConnName()
-			},
-		)
-		result.Values[VKeyInterval] = interval
-		result.Values[VKeyPlugin] = plugins
-		result.Message = "get info finished"
-	default:
+func cmdCancel(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	id, _ := strconv.ParseUint(cmd.KwArgs["id"], 10, 64)
+
+	if err := svr.cancelInflight(cmd.session, id); err != nil {
 		result.Rtn = 1
-		result.Message = "unsupported command"
+		result.Message = err.Error()
+		return err
 	}
 
-	return
+	result.Message = fmt.Sprintf("command %d cancelled", id)
+
+	return nil
+}
+
+func cmdSchema(_ context.Context, _ *CtlServer, cmd *Command, result *Result) error {
+	specs := CommandSpecs()
+
+	if name := cmd.KwArgs["name"]; name != "" {
+		spec, exist := GetCommandSpec(name)
+		if !exist {
+			result.Rtn = 1
+			result.Message = fmt.Sprintf("%s: %s", ErrUnknownCommand, name)
+			return nil
+		}
+
+		specs = []*CommandSpec{spec}
+	}
+
+	result.Values[VKeySchema] = specs
+	result.Message = fmt.Sprintf("%d commands found", len(specs))
+
+	return nil
 }
@@ -0,0 +1,148 @@
+package ctl
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+	"github.com/frozenpine/latency4go/libs"
+)
+
+// blockingPlugin 销毁时阻塞至 release 关闭，记录销毁后的插件调用
+type blockingPlugin struct {
+	stopping chan struct{}
+	release  chan struct{}
+
+	stopped    atomic.Bool
+	afterCalls atomic.Int32
+}
+
+func (p *blockingPlugin) called() {
+	if p.stopped.Load() {
+		p.afterCalls.Add(1)
+	}
+}
+
+func (p *blockingPlugin) SetLogger(slog.Level, string, int, int) error { return nil }
+func (p *blockingPlugin) Init(context.Context, string) error           { return nil }
+func (p *blockingPlugin) Join() error                                  { return nil }
+
+func (p *blockingPlugin) ReportFronts(...string) error {
+	p.called()
+	return nil
+}
+
+func (p *blockingPlugin) Seats() []libs.Seat {
+	p.called()
+	return []libs.Seat{{Idx: 1, Addr: "tcp://127.0.0.1:1"}}
+}
+
+func (p *blockingPlugin) Priority() [][]int {
+	p.called()
+	return [][]int{{1}}
+}
+
+func (p *blockingPlugin) Stop() {
+	close(p.stopping)
+	<-p.release
+	p.stopped.Store(true)
+}
+
+func TestSeatsDuringUnplugin(t *testing.T) {
+	plugin := &blockingPlugin{
+		stopping: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+
+	container, err := libs.RegisterPlugin("blocking", plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	instance := &latency4go.LatencyClient{}
+	if err := instance.AddReporter(
+		"blocking", func(s *latency4go.State) error {
+			return container.ReportFronts(s.AddrList...)
+		},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	svr, err := NewCtlServer(
+		t.Context(), (&CtlSvrHdlConfig{}).Tcp("127.0.0.1:0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	svr.instance.Store(instance)
+	go svr.runForever()
+	defer svr.cancel()
+
+	client, err := NewCtlTcpClient(
+		svr.handlers[0].(*CtlTcpHandler).listen.Addr().String() + "?reconnect=false",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	if result, err := client.Call(t.Context(), &Command{
+		Name: "seats", KwArgs: map[string]string{"plugin": "blocking"},
+	}); err != nil || result.Rtn != 0 {
+		t.Fatalf("seats command failed: %+v, %+v", result, err)
+	}
+
+	unloaded := make(chan *Result, 1)
+	go func() {
+		result, _ := client.Call(t.Context(), &Command{
+			Name: "unplugin", KwArgs: map[string]string{"plugin": "blocking"},
+		})
+		unloaded <- result
+	}()
+
+	select {
+	case <-plugin.stopping:
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait plugin stopping timeout")
+	}
+
+	// 插件销毁期间的查询等待 unplugin 结束，不再调用已销毁的插件
+	queried := make(chan *Result, 2)
+	for _, name := range []string{"seats", "priority"} {
+		go func() {
+			result, _ := client.Call(t.Context(), &Command{
+				Name: name, KwArgs: map[string]string{"plugin": "blocking"},
+			})
+			queried <- result
+		}()
+	}
+
+	reported := make(chan error, 1)
+	go func() { reported <- container.ReportFronts("tcp://127.0.0.1:1") }()
+
+	time.Sleep(time.Millisecond * 100)
+	close(plugin.release)
+
+	if result := <-unloaded; result == nil || result.Rtn != 0 {
+		t.Fatalf("unplugin command failed: %+v", result)
+	}
+
+	for range 2 {
+		if result := <-queried; result == nil || result.Rtn == 0 {
+			t.Fatalf("query unloaded plugin not failed: %+v", result)
+		}
+	}
+
+	if err := <-reported; err != libs.ErrPluginStopped {
+		t.Fatalf("report to stopped plugin not failed: %v", err)
+	}
+
+	if calls := plugin.afterCalls.Load(); calls != 0 {
+		t.Fatalf("stopped plugin called %d times", calls)
+	}
+}
@@ -2,11 +2,102 @@ package ctl
 
 import (
 	"log/slog"
+	"net/url"
 	"strings"
+	"time"
 )
 
+// splitConnOptions 拆分连接字串中的地址与 `?` 之后的选项参数
+func splitConnOptions(conn string) (string, url.Values, error) {
+	addr, query, _ := strings.Cut(conn, "?")
+
+	opts, err := url.ParseQuery(query)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return addr, opts, nil
+}
+
 type CtlSvrHdlConfig struct {
-	handlers []Handler
+	handlers   []Handler
+	auditPath  string
+	logHandler *LogHandler
+
+	historySize  int
+	rollbackHold time.Duration
+	approvals    map[string]time.Duration
+}
+
+// Audit 指定命令审计日志文件，日志以 JSON Lines 格式追加写入
+func (cfg *CtlSvrHdlConfig) Audit(path string) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	cfg.auditPath = path
+
+	return cfg
+}
+
+// Logs 指定日志分流器，服务端日志经由 hdl 广播至订阅 log 主题的连接
+func (cfg *CtlSvrHdlConfig) Logs(hdl *LogHandler) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	cfg.logHandler = hdl
+
+	return cfg
+}
+
+// Rollback 指定每个插件保留的已上报状态数，及 rollback 命令后暂停周期上报的时长
+func (cfg *CtlSvrHdlConfig) Rollback(size int, hold time.Duration) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	cfg.historySize = size
+	cfg.rollbackHold = hold
+
+	return cfg
+}
+
+// Approval 指定开启审批模式的插件及提案过期时长，插件 reporter 注册后生效，
+// LatencyClient 重新启动后保持
+func (cfg *CtlSvrHdlConfig) Approval(ttl time.Duration, names ...string) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	if cfg.approvals == nil {
+		cfg.approvals = map[string]time.Duration{}
+	}
+
+	for _, name := range names {
+		cfg.approvals[name] = ttl
+	}
+
+	return cfg
+}
+
+// Command 注册嵌入程序的自定义命令，同名命令已注册时配置失败
+func (cfg *CtlSvrHdlConfig) Command(spec CommandSpec) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	if err := RegisterCommand(spec); err != nil {
+		slog.Error(
+			"register ctl command failed",
+			slog.Any("error", err),
+			slog.String("cmd", spec.Name),
+		)
+
+		return nil
+	}
+
+	return cfg
 }
 
 func (cfg *CtlSvrHdlConfig) Ipc(conn string) *CtlSvrHdlConfig {
@@ -53,3 +144,72 @@ func (cfg *CtlSvrHdlConfig) Tcp(conn string) *CtlSvrHdlConfig {
 		return cfg
 	}
 }
+
+func (cfg *CtlSvrHdlConfig) Unix(conn string) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	slog.Info("creating unix ctl handler", slog.String("conn", conn))
+
+	if unix, err := NewCtlUnixHandler(
+		strings.TrimPrefix(conn, "unix://"),
+	); err != nil {
+		slog.Error(
+			"create unix ctl handler failed",
+			slog.Any("error", err),
+		)
+
+		return nil
+	} else {
+		cfg.handlers = append(cfg.handlers, unix)
+
+		return cfg
+	}
+}
+
+func (cfg *CtlSvrHdlConfig) Http(conn string) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	slog.Info("creating http ctl handler", slog.String("conn", conn))
+
+	if http, err := NewCtlHttpHandler(
+		strings.TrimPrefix(conn, "http://"),
+	); err != nil {
+		slog.Error(
+			"create http ctl handler failed",
+			slog.Any("error", err),
+		)
+
+		return nil
+	} else {
+		cfg.handlers = append(cfg.handlers, http)
+
+		return cfg
+	}
+}
+
+func (cfg *CtlSvrHdlConfig) Tls(conn string) *CtlSvrHdlConfig {
+	if cfg == nil {
+		return nil
+	}
+
+	slog.Info("creating tls ctl handler", slog.String("conn", conn))
+
+	if tls, err := NewCtlTlsHandler(
+		strings.TrimPrefix(conn, "tls://"),
+	); err != nil {
+		slog.Error(
+			"create tls ctl handler failed",
+			slog.Any("error", err),
+		)
+
+		return nil
+	} else {
+		cfg.handlers = append(cfg.handlers, tls)
+
+		return cfg
+	}
+}
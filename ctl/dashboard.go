@@ -0,0 +1,19 @@
+package ctl
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed web
+var webAssets embed.FS
+
+func dashboardHandler() http.Handler {
+	assets, err := fs.Sub(webAssets, "web")
+	if err != nil {
+		panic(err)
+	}
+
+	return http.FileServerFS(assets)
+}
@@ -0,0 +1,175 @@
+package ctl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync/atomic"
+)
+
+var (
+	ErrMsgTooLarge    = errors.New("message too large")
+	ErrInvalidFraming = errors.New("invalid framing")
+)
+
+// framing 流式连接的消息分帧方式
+type framing uint32
+
+const (
+	// framingLine 换行分隔的 JSON，兼容旧版本客户端及服务端
+	framingLine framing = iota
+	// framingLength 4 字节大端长度前缀 + JSON
+	framingLength
+)
+
+const (
+	frameHeaderSize = 4
+	// defaultMaxMsgSize 单条消息的默认最大长度
+	defaultMaxMsgSize = 16 << 20
+)
+
+var framingNames = [...]string{"line", "length"}
+
+func (f framing) String() string {
+	if int(f) < len(framingNames) {
+		return framingNames[f]
+	}
+
+	return fmt.Sprintf("framing(%d)", f)
+}
+
+func (f framing) MarshalText() ([]byte, error) {
+	return []byte(f.String()), nil
+}
+
+func (f *framing) UnmarshalText(v []byte) error {
+	for idx, name := range framingNames {
+		if string(v) == name {
+			*f = framing(idx)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidFraming, v)
+}
+
+// parseMaxMsgSize 解析 maxsize 连接参数，未指定时为 defaultMaxMsgSize
+func parseMaxMsgSize(v string) (int, error) {
+	if v == "" {
+		return defaultMaxMsgSize, nil
+	}
+
+	size, err := strconv.Atoi(v)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("%w: invalid max msg size %s", ErrInvalidMsgData, v)
+	}
+
+	return size, nil
+}
+
+// frameCodec 按协商的分帧方式读写消息，握手完成前使用 framingLine
+type frameCodec struct {
+	rd      *bufio.Reader
+	framing atomic.Uint32
+	maxSize atomic.Int64
+}
+
+func newFrameCodec(r io.Reader, maxSize int) *frameCodec {
+	codec := frameCodec{rd: bufio.NewReader(r)}
+	codec.maxSize.Store(int64(maxSize))
+
+	return &codec
+}
+
+func (codec *frameCodec) getFraming() framing {
+	return framing(codec.framing.Load())
+}
+
+func (codec *frameCodec) setFraming(f framing, maxSize int) {
+	codec.maxSize.Store(int64(maxSize))
+	codec.framing.Store(uint32(f))
+}
+
+func (codec *frameCodec) readFrame() ([]byte, error) {
+	maxSize := int(codec.maxSize.Load())
+
+	if codec.getFraming() == framingLength {
+		var header [frameHeaderSize]byte
+		if _, err := io.ReadFull(codec.rd, header[:]); err != nil {
+			return nil, err
+		}
+
+		size := int(binary.BigEndian.Uint32(header[:]))
+		if size > maxSize {
+			return nil, fmt.Errorf("%w: %d > %d", ErrMsgTooLarge, size, maxSize)
+		}
+
+		frame := make([]byte, size)
+		if _, err := io.ReadFull(codec.rd, frame); err != nil {
+			return nil, err
+		}
+
+		return frame, nil
+	}
+
+	var line []byte
+	for {
+		chunk, err := codec.rd.ReadSlice('\n')
+		line = append(line, chunk...)
+
+		if len(line) > maxSize+1 {
+			return nil, fmt.Errorf("%w: line exceeds %d", ErrMsgTooLarge, maxSize)
+		}
+
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
+
+func (codec *frameCodec) readMsg() (*Message, error) {
+	frame, err := codec.readFrame()
+	if err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(frame, &msg); err != nil {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidMsgData, err)
+	}
+
+	return &msg, nil
+}
+
+// encode 将消息编码为当前分帧方式的数据帧
+func (codec *frameCodec) encode(msg *Message) ([]byte, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxSize := int(codec.maxSize.Load()); len(data) > maxSize {
+		return nil, fmt.Errorf(
+			"%w: %s %d > %d", ErrMsgTooLarge, msg.msgType, len(data), maxSize,
+		)
+	}
+
+	if codec.getFraming() == framingLength {
+		frame := make([]byte, frameHeaderSize+len(data))
+		binary.BigEndian.PutUint32(frame, uint32(len(data)))
+		copy(frame[frameHeaderSize:], data)
+
+		return frame, nil
+	}
+
+	return append(data, '\n'), nil
+}
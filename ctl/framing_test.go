@@ -0,0 +1,105 @@
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFraming(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0?maxsize=1048576")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	go func() {
+		for msg := range hdl.Commands() {
+			cmd, _ := msg.GetCommand()
+			data, _ := json.Marshal(&Result{
+				CmdName: cmd.Name,
+				Message: cmd.KwArgs["payload"],
+			})
+
+			hdl.Publish(&Message{
+				msgID:   msg.msgID,
+				msgType: MsgResult,
+				data:    data,
+			}, time.Second)
+		}
+	}()
+
+	addr := hdl.listen.Addr().String()
+	// 超出 bufio.Scanner 默认 64KB 的行长度限制
+	payload := strings.Repeat("x", 256<<10)
+
+	for _, expect := range []framing{framingLength, framingLine} {
+		client, err := NewCtlTcpClient(addr + "?framing=" + expect.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Init(t.Context(), "test client", func() { go client.recv() })
+		defer client.Release()
+
+		if f := client.codec.getFraming(); f != expect {
+			t.Fatalf("framing mismatch: expect %s, got %s", expect, f)
+		}
+
+		result, err := client.Call(t.Context(), &Command{
+			Name: "state", KwArgs: map[string]string{"payload": payload},
+		})
+		if err != nil {
+			t.Fatal(err)
+		} else if result.Message != payload {
+			t.Fatalf("%s framing payload mismatch: %d", expect, len(result.Message))
+		}
+
+		if expect != framingLength {
+			continue
+		}
+
+		// 握手协商后客户端以服务端的长度上限校验待发消息
+		if _, err = client.Call(t.Context(), &Command{
+			Name: "state", KwArgs: map[string]string{"payload": strings.Repeat(payload, 4)},
+		}); !errors.Is(err, ErrMsgTooLarge) {
+			t.Fatalf("oversize message not rejected: %+v", err)
+		}
+	}
+
+	// 未发送握手消息的旧版客户端
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	data, _ := json.Marshal(&Command{
+		Name: "state", KwArgs: map[string]string{"payload": "legacy"},
+	})
+	line, _ := json.Marshal(&Message{msgID: 1, msgType: MsgCommand, data: data})
+
+	if _, err = conn.Write(append(line, '\n')); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+	if line, err = bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg Message
+	if err = json.Unmarshal(line, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if result, err := msg.GetResult(); err != nil {
+		t.Fatal(err)
+	} else if result.Message != "legacy" {
+		t.Fatalf("legacy result mismatch: %s", result.Message)
+	}
+}
@@ -1,14 +1,24 @@
 package ctl
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/frozenpine/msgqueue/channel"
 	"github.com/frozenpine/msgqueue/core"
 )
 
+var (
+	ErrHandlerReleased = errors.New("ctl handler released")
+	ErrCtlServerBusy   = errors.New("ctl server busy")
+)
+
 type Handler interface {
 	core.Upstream[*Message]
 	core.Producer[*Message]
@@ -18,18 +28,77 @@ type Handler interface {
 
 	ConnName() string
 	ConnCount() int
+	// QueueStats 各连接发送队列的统计信息
+	QueueStats() []QueueStat
+	// Sessions 当前已连接的客户端会话
+	Sessions() []SessionInfo
+	// Kick 断开 remote 对应的客户端连接
+	Kick(remote string) error
 }
 
 type messageWriter interface {
 	Write(*Message) error
 }
 
+// heartbeatTimeoutFactor 连续未收到消息超过 heartbeatTimeoutFactor 个心跳周期即视为连接失效
+const heartbeatTimeoutFactor = 3
+
+// liveWriter 支持心跳检测的连接，失效时由 Handler 主动关闭
+type liveWriter interface {
+	messageWriter
+
+	lastSeen() time.Time
+	Close() error
+}
+
+// session 命令来源连接的会话信息，由 Handler 在转发命令时附加
+type session struct {
+	handler  string
+	remote   string
+	identity string
+	role     Role
+	subs     *subscriptions
+
+	connected time.Time
+	lastCmd   atomic.Pointer[sessionCommand]
+}
+
+// sessionWriter 附带会话信息的连接，广播消息按会话订阅过滤
+type sessionWriter interface {
+	messageWriter
+
+	getSession() *session
+}
+
+// authorize 校验会话角色是否允许执行命令，未附加会话的消息不做校验
+func (sess *session) authorize(cmdName string) error {
+	if sess == nil {
+		return nil
+	}
+
+	if required := commandRole(cmdName); sess.role < required {
+		return fmt.Errorf(
+			"%w: %s requires %s role, got %s",
+			ErrPermissionDenied, cmdName, required, sess.role,
+		)
+	}
+
+	return nil
+}
+
 type ctlBaseHandler struct {
 	channel.MemoChannel[*Message]
 
-	hdlName         string
-	connName        string
-	hdlCommands     chan *Message
+	hdlName     string
+	connName    string
+	role        Role
+	roles       map[string]Role
+	heartbeat   time.Duration
+	queue       queueOptions
+	hdlDone     chan struct{}
+	hdlCommands chan *Message
+	// hdlCmdLock 保护 hdlCommands 的发送与关闭，避免释放时向已关闭的通道发送命令
+	hdlCmdLock      sync.RWMutex
 	hdlConnCount    atomic.Int32
 	hdlConnections  sync.Map
 	hdlCommandCache sync.Map
@@ -43,6 +112,59 @@ func (hdl *ctlBaseHandler) ConnName() string {
 	return hdl.connName
 }
 
+// parseBaseOptions 解析各 Handler 通用的连接参数
+//
+//	role: 连接默认角色，未指定时为 admin
+//	roles: 角色文件，按认证身份覆盖连接默认角色
+//	heartbeat: 心跳周期，未指定时不发送心跳
+//	sendq: 连接发送队列容量，默认 256
+//	overflow: 发送队列满时的处理策略，drop | coalesce | disconnect，默认 drop
+func (hdl *ctlBaseHandler) parseBaseOptions(opts url.Values) (err error) {
+	hdl.role = RoleAdmin
+
+	if v := opts.Get("role"); v != "" {
+		if hdl.role, err = ParseRole(v); err != nil {
+			return
+		}
+	}
+
+	if path := opts.Get("roles"); path != "" {
+		if hdl.roles, err = loadRoles(path); err != nil {
+			return
+		}
+	}
+
+	if v := opts.Get("heartbeat"); v != "" {
+		if hdl.heartbeat, err = time.ParseDuration(v); err != nil {
+			return
+		}
+	}
+
+	if hdl.queue, err = parseQueueOptions(opts); err != nil {
+		return
+	}
+
+	return
+}
+
+func (hdl *ctlBaseHandler) newSession(remote, identity string) *session {
+	sess := session{
+		handler:  hdl.hdlName,
+		remote:   remote,
+		identity: identity,
+		role:     hdl.role,
+		subs:     newSubscriptions(),
+
+		connected: time.Now(),
+	}
+
+	if role, exist := hdl.roles[identity]; exist && identity != "" {
+		sess.role = role
+	}
+
+	return &sess
+}
+
 func (hdl *ctlBaseHandler) addConn(name string, wr messageWriter) {
 	if _, exist := hdl.hdlConnections.LoadOrStore(name, wr); exist {
 		slog.Error(
@@ -69,10 +191,93 @@ func (hdl *ctlBaseHandler) ConnCount() int {
 	return int(hdl.hdlConnCount.Load())
 }
 
+func (hdl *ctlBaseHandler) QueueStats() []QueueStat {
+	stats := []QueueStat{}
+
+	hdl.hdlConnections.Range(func(key, value any) bool {
+		wr, ok := value.(queuedWriter)
+		if !ok {
+			return true
+		}
+
+		stat := wr.queueStats()
+		stat.Handler = hdl.hdlName
+		stat.Remote = fmt.Sprint(key)
+		if sw, ok := value.(sessionWriter); ok && sw.getSession() != nil {
+			stat.Identity = sw.getSession().identity
+		}
+
+		stats = append(stats, stat)
+		return true
+	})
+
+	return stats
+}
+
 func (hdl *ctlBaseHandler) baseStart() {
 	hdl.hdlCommands = make(chan *Message, 10)
+	hdl.hdlDone = make(chan struct{})
 
 	go hdl.dispatchResults()
+
+	if hdl.heartbeat > 0 {
+		go hdl.keepalive()
+	}
+}
+
+// keepalive 周期性向支持心跳的连接发送 Ping，并关闭超时未响应的连接
+func (hdl *ctlBaseHandler) keepalive() {
+	ticker := time.NewTicker(hdl.heartbeat)
+	defer ticker.Stop()
+
+	timeout := hdl.heartbeat * heartbeatTimeoutFactor
+
+	for {
+		select {
+		case <-hdl.hdlDone:
+			return
+		case <-ticker.C:
+		}
+
+		ping, err := newPingMessage()
+		if err != nil {
+			slog.Error(
+				"create ping message failed",
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		hdl.hdlConnections.Range(func(key, value any) bool {
+			wr, ok := value.(liveWriter)
+			if !ok {
+				return true
+			}
+
+			if idle := time.Since(wr.lastSeen()); idle > timeout {
+				slog.Warn(
+					"evict dead ctl connection",
+					slog.String("handler", hdl.hdlName),
+					slog.Any("remote", key),
+					slog.Duration("idle", idle),
+				)
+
+				// 连接关闭后由读协程负责移除连接
+				wr.Close()
+				return true
+			}
+
+			if err := wr.Write(ping); err != nil {
+				slog.Error(
+					"write ping msg failed",
+					slog.Any("error", err),
+					slog.Any("remote", key),
+				)
+			}
+
+			return true
+		})
+	}
 }
 
 func (hdl *ctlBaseHandler) dispatchResults() {
@@ -80,7 +285,7 @@ func (hdl *ctlBaseHandler) dispatchResults() {
 
 	for msg := range results {
 		switch msg.GetType() {
-		case MsgBroadCast:
+		case MsgBroadCast, MsgEvent, MsgLog:
 			hdl.hdlConnections.Range(func(key, value any) bool {
 				wr, ok := value.(messageWriter)
 
@@ -91,20 +296,35 @@ func (hdl *ctlBaseHandler) dispatchResults() {
 					)
 
 					hdl.delConn(key)
+					return true
 				}
 
-				err := wr.Write(msg)
-
-				if err != nil {
-					slog.Error(
-						"write broadcast msg failed",
-						slog.Any("error", err),
-						slog.Any("identity", key),
-					)
+				for _, out := range topicMessages(wr, msg) {
+					if err := wr.Write(out); err != nil {
+						slog.Error(
+							"write broadcast msg failed",
+							slog.Any("error", err),
+							slog.Any("identity", key),
+						)
+						break
+					}
 				}
 
 				return true
 			})
+		case MsgProgress:
+			// 命令执行完成前的进度通知，写入命令来源连接但保留路由
+			if value, loaded := hdl.hdlCommandCache.Load(msg.msgID); loaded {
+				if wr, ok := value.(messageWriter); ok {
+					if err := wr.Write(msg); err != nil {
+						slog.Error(
+							"write command progress failed",
+							slog.Any("error", err),
+							slog.Any("msg", msg),
+						)
+					}
+				}
+			}
 		case MsgResult:
 			if value, loaded := hdl.hdlCommandCache.LoadAndDelete(
 				msg.msgID,
@@ -147,12 +367,57 @@ func (hdl *ctlBaseHandler) dispatchResults() {
 	)
 }
 
+// topicMessages 按连接会话的订阅转换广播消息，无会话的连接仅接收 State 广播
+func topicMessages(wr messageWriter, msg *Message) []*Message {
+	if sw, ok := wr.(sessionWriter); ok {
+		if sess := sw.getSession(); sess != nil && sess.subs != nil {
+			return sess.subs.filter(msg)
+		}
+	}
+
+	if msg.msgType == MsgBroadCast && (msg.topic == "" || msg.topic == TopicState) {
+		return []*Message{msg}
+	}
+
+	return nil
+}
+
 func (hdl *ctlBaseHandler) Commands() <-chan *Message {
 	return hdl.hdlCommands
 }
 
+// sendCommand 将命令投递至 CtlServer，Handler 已释放或投递超时时返回错误
+func (hdl *ctlBaseHandler) sendCommand(
+	ctx context.Context, msg *Message, timeout time.Duration,
+) error {
+	hdl.hdlCmdLock.RLock()
+	defer hdl.hdlCmdLock.RUnlock()
+
+	select {
+	case <-hdl.hdlDone:
+		return ErrHandlerReleased
+	default:
+	}
+
+	select {
+	case hdl.hdlCommands <- msg:
+		return nil
+	case <-hdl.hdlDone:
+		return ErrHandlerReleased
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(timeout):
+		return ErrCtlServerBusy
+	}
+}
+
 func (hdl *ctlBaseHandler) baseRelease() {
+	close(hdl.hdlDone)
+
+	// 等待投递中的命令退出后再关闭命令通道
+	hdl.hdlCmdLock.Lock()
 	close(hdl.hdlCommands)
+	hdl.hdlCmdLock.Unlock()
 
 	hdl.MemoChannel.Release()
 }
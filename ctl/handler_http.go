@@ -0,0 +1,553 @@
+package ctl
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// streamTicketTTL 推送连接票据的有效期
+	streamTicketTTL = time.Second * 30
+	// httpMaxBodyBytes REST 请求体的长度上限
+	httpMaxBodyBytes = 1 << 20
+)
+
+var (
+	ErrHttpResultTimeout = errors.New("wait command result timeout")
+	ErrHttpUnauthorized  = errors.New("unauthorized")
+	ErrHttpContentType   = errors.New("content type must be application/json")
+	ErrHttpCrossOrigin   = errors.New("cross origin request not allowed")
+	ErrHttpCancel        = errors.New("rest command is cancelled by aborting its request")
+)
+
+// httpRoute 定义 REST 接口与 Command 的映射关系
+type httpRoute struct {
+	method  string
+	path    string
+	cmdName string
+}
+
+var httpRoutes = []httpRoute{
+	{http.MethodGet, "/api/info", "info"},
+	{http.MethodGet, "/api/state", "state"},
+	{http.MethodPost, "/api/query", "query"},
+	{http.MethodPut, "/api/config", "config"},
+	{http.MethodPut, "/api/period", "period"},
+	{http.MethodPost, "/api/suspend", "suspend"},
+	{http.MethodPost, "/api/resume", "resume"},
+	{http.MethodPost, "/api/start", "start"},
+	{http.MethodPost, "/api/stop", "stop"},
+	{http.MethodPost, "/api/plugins/{plugin}", "plugin"},
+	{http.MethodDelete, "/api/plugins/{plugin}", "unplugin"},
+	{http.MethodGet, "/api/plugins/{plugin}/seats", "seats"},
+	{http.MethodGet, "/api/plugins/{plugin}/priority", "priority"},
+	{http.MethodPost, "/api/push", "push"},
+	{http.MethodDelete, "/api/push", "unpin"},
+	{http.MethodGet, "/api/history", "history"},
+	{http.MethodPost, "/api/rollback", "rollback"},
+	{http.MethodPut, "/api/approval/{plugin}", "approval"},
+	{http.MethodGet, "/api/proposals", "proposals"},
+	{http.MethodPost, "/api/proposals/{id}", "approve"},
+	{http.MethodDelete, "/api/proposals/{id}", "reject"},
+	{http.MethodGet, "/api/audit", "audit"},
+	{http.MethodGet, "/api/sessions", "sessions"},
+	{http.MethodDelete, "/api/sessions", "kick"},
+	{http.MethodGet, "/api/schema", "schema"},
+	// 自定义命令，命令名称取自路径
+	{http.MethodPost, "/api/commands/{command}", ""},
+}
+
+type CtlHttpHandler struct {
+	ctlBaseHandler
+
+	listen  net.Listener
+	server  *http.Server
+	timeout time.Duration
+	token   string
+	origins []string
+	cmdSeq  atomic.Uint64
+	// tickets 推送连接的一次性票据及其过期时间
+	tickets sync.Map
+
+	streamCtx    context.Context
+	streamCancel context.CancelFunc
+}
+
+type httpMsgWriter struct {
+	result chan *Message
+}
+
+func (wr *httpMsgWriter) Write(msg *Message) error {
+	// 单次请求仅返回最终结果，忽略执行进度
+	if msg.msgType != MsgResult {
+		return nil
+	}
+
+	select {
+	case wr.result <- msg:
+		return nil
+	default:
+		return errors.New("http result already written")
+	}
+}
+
+func httpKwArgs(w http.ResponseWriter, r *http.Request) (map[string]string, error) {
+	kwargs := map[string]string{}
+
+	for k, v := range r.URL.Query() {
+		kwargs[k] = strings.Join(v, ",")
+	}
+
+	if r.ContentLength != 0 {
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, httpMaxBodyBytes))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(data) > 0 {
+			values := map[string]string{}
+
+			// 非字符串字典的 JSON 视为完整的 QueryConfig
+			if err := json.Unmarshal(data, &values); err != nil {
+				kwargs["config"] = string(data)
+			} else {
+				for k, v := range values {
+					kwargs[k] = v
+				}
+			}
+		}
+	}
+
+	if plugin := r.PathValue("plugin"); plugin != "" {
+		kwargs["plugin"] = plugin
+	}
+
+	if id := r.PathValue("id"); id != "" {
+		kwargs["id"] = id
+	}
+
+	return kwargs, nil
+}
+
+func writeHttpResult(w http.ResponseWriter, status int, data []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if _, err := w.Write(data); err != nil {
+		slog.Error(
+			"write http response failed",
+			slog.Any("error", err),
+		)
+	}
+}
+
+func writeHttpError(w http.ResponseWriter, status int, cmdName string, err error) {
+	data, _ := json.Marshal(&Result{
+		Rtn:     1,
+		Message: err.Error(),
+		CmdName: cmdName,
+	})
+
+	writeHttpResult(w, status, data)
+}
+
+// authorized 校验请求中的 Bearer Token，未配置 Token 时不做校验
+func (httpHdl *CtlHttpHandler) authorized(r *http.Request) bool {
+	if httpHdl.token == "" {
+		return true
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare(
+		[]byte(token), []byte(httpHdl.token),
+	) == 1
+}
+
+// issueTicket 签发推送连接使用的一次性票据，同时清理已过期的票据
+func (httpHdl *CtlHttpHandler) issueTicket() (string, time.Time, error) {
+	ticket, err := newNonce()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	httpHdl.tickets.Range(func(k, v any) bool {
+		if now.After(v.(time.Time)) {
+			httpHdl.tickets.Delete(k)
+		}
+		return true
+	})
+
+	expire := now.Add(streamTicketTTL)
+	httpHdl.tickets.Store(ticket, expire)
+
+	return ticket, expire, nil
+}
+
+// streamAuthorized 校验推送连接，EventSource / WebSocket 无法自定义请求头，
+// 可通过 ticket 查询参数传递 /api/ticket 签发的一次性票据，避免令牌出现在 URL 中
+func (httpHdl *CtlHttpHandler) streamAuthorized(r *http.Request) bool {
+	if httpHdl.authorized(r) {
+		return true
+	}
+
+	ticket := r.URL.Query().Get("ticket")
+	if ticket == "" {
+		return false
+	}
+
+	expire, exist := httpHdl.tickets.LoadAndDelete(ticket)
+
+	return exist && time.Now().Before(expire.(time.Time))
+}
+
+// jsonRequest 判断请求是否声明 JSON 内容类型，
+// 浏览器跨站提交的表单无法声明该类型，跨站脚本声明该类型则须经 CORS 预检
+func jsonRequest(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	return mediaType == "application/json"
+}
+
+// allowedOrigin 校验浏览器请求的 Origin，未携带 Origin 的非浏览器请求不做校验，
+// Origin 主机与请求主机一致或在 origin 连接参数列表中时允许
+func (httpHdl *CtlHttpHandler) allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+
+	return slices.ContainsFunc(httpHdl.origins, func(allowed string) bool {
+		return allowed == "*" || strings.EqualFold(allowed, u.Host)
+	})
+}
+
+// requestSession 生成请求会话，未通过校验的请求至多拥有 viewer 角色
+func (httpHdl *CtlHttpHandler) requestSession(remote string, authed bool) *session {
+	sess := httpHdl.newSession(remote, "")
+
+	if !authed {
+		sess.role = min(sess.role, RoleViewer)
+	}
+
+	return sess
+}
+
+func (httpHdl *CtlHttpHandler) handleAuth(w http.ResponseWriter, r *http.Request) {
+	if !httpHdl.authorized(r) {
+		writeHttpError(w, http.StatusUnauthorized, "", ErrHttpUnauthorized)
+		return
+	}
+
+	data, _ := json.Marshal(&struct{ Role Role }{
+		Role: httpHdl.requestSession(r.RemoteAddr, true).role,
+	})
+
+	writeHttpResult(w, http.StatusOK, data)
+}
+
+func (httpHdl *CtlHttpHandler) handleTicket(w http.ResponseWriter, r *http.Request) {
+	if !httpHdl.allowedOrigin(r) {
+		writeHttpError(w, http.StatusForbidden, "", ErrHttpCrossOrigin)
+		return
+	}
+
+	if !httpHdl.authorized(r) {
+		writeHttpError(w, http.StatusUnauthorized, "", ErrHttpUnauthorized)
+		return
+	}
+
+	ticket, expire, err := httpHdl.issueTicket()
+	if err != nil {
+		writeHttpError(w, http.StatusInternalServerError, "", err)
+		return
+	}
+
+	data, _ := json.Marshal(&struct {
+		Ticket  string
+		Expires time.Time
+	}{
+		Ticket:  ticket,
+		Expires: expire,
+	})
+
+	writeHttpResult(w, http.StatusOK, data)
+}
+
+func (httpHdl *CtlHttpHandler) handleCommand(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cmdName := name
+		if cmdName == "" {
+			cmdName = r.PathValue("command")
+		}
+
+		if !httpHdl.allowedOrigin(r) {
+			writeHttpError(w, http.StatusForbidden, cmdName, ErrHttpCrossOrigin)
+			return
+		}
+
+		// 每个请求使用独立会话，cancel 命令无法指向其他请求的命令
+		if cmdName == "cancel" {
+			writeHttpError(w, http.StatusBadRequest, cmdName, ErrHttpCancel)
+			return
+		}
+
+		sess := httpHdl.requestSession(r.RemoteAddr, httpHdl.authorized(r))
+		if err := sess.authorize(cmdName); err != nil {
+			status := http.StatusForbidden
+			if !httpHdl.authorized(r) {
+				status = http.StatusUnauthorized
+			}
+
+			writeHttpError(w, status, cmdName, err)
+			return
+		}
+
+		// 变更类请求须声明 JSON 内容类型，避免跨站表单或简单请求执行命令
+		if r.Method != http.MethodGet && !jsonRequest(r) {
+			writeHttpError(
+				w, http.StatusUnsupportedMediaType, cmdName, ErrHttpContentType,
+			)
+			return
+		}
+
+		kwargs, err := httpKwArgs(w, r)
+		if err != nil {
+			status := http.StatusBadRequest
+			if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+				status = http.StatusRequestEntityTooLarge
+			}
+
+			writeHttpError(w, status, cmdName, err)
+			return
+		}
+
+		cmdData, err := json.Marshal(&Command{
+			Name:   cmdName,
+			KwArgs: kwargs,
+		})
+		if err != nil {
+			writeHttpError(w, http.StatusBadRequest, cmdName, err)
+			return
+		}
+
+		msg := Message{
+			msgID:   httpHdl.cmdSeq.Add(1),
+			msgType: MsgCommand,
+			data:    cmdData,
+			session: sess,
+		}
+		wr := &httpMsgWriter{result: make(chan *Message, 1)}
+
+		slog.Info(
+			"http ctl command received",
+			slog.String("remote", r.RemoteAddr),
+			slog.String("cmd", cmdName),
+			slog.Any("kwargs", kwargs),
+		)
+
+		httpHdl.hdlCommandCache.Store(msg.msgID, wr)
+
+		if err := httpHdl.sendCommand(
+			r.Context(), &msg, time.Second*5,
+		); err != nil {
+			httpHdl.hdlCommandCache.Delete(msg.msgID)
+			if r.Context().Err() != nil {
+				return
+			}
+
+			slog.Warn(
+				"send message from HTTP to ctl server failed",
+				slog.Any("error", err),
+			)
+			writeHttpError(w, http.StatusServiceUnavailable, cmdName, err)
+			return
+		}
+
+		select {
+		case rsp := <-wr.result:
+			result, err := rsp.GetResult()
+			if err != nil || result == nil {
+				writeHttpError(
+					w, http.StatusInternalServerError, cmdName,
+					fmt.Errorf("%w: %+v", ErrInvalidMsgData, err),
+				)
+				return
+			}
+
+			status := http.StatusOK
+			switch result.Rtn {
+			case 0:
+			case RtnDenied:
+				status = http.StatusForbidden
+			default:
+				status = http.StatusBadRequest
+			}
+
+			writeHttpResult(w, status, rsp.data)
+		case <-r.Context().Done():
+			httpHdl.hdlCommandCache.Delete(msg.msgID)
+			httpHdl.abandonCommand(sess, msg.msgID)
+		case <-time.After(httpHdl.timeout):
+			httpHdl.hdlCommandCache.Delete(msg.msgID)
+			httpHdl.abandonCommand(sess, msg.msgID)
+			writeHttpError(
+				w, http.StatusGatewayTimeout, cmdName, ErrHttpResultTimeout,
+			)
+		}
+	}
+}
+
+// abandonCommand 请求中断或等待超时后取消会话中执行中的命令，取消结果直接丢弃
+func (httpHdl *CtlHttpHandler) abandonCommand(sess *session, id uint64) {
+	cmdData, err := json.Marshal(&Command{
+		Name:   "cancel",
+		KwArgs: map[string]string{"id": strconv.FormatUint(id, 10)},
+	})
+	if err != nil {
+		return
+	}
+
+	msg := Message{
+		msgID:   httpHdl.cmdSeq.Add(1),
+		msgType: MsgCommand,
+		data:    cmdData,
+		session: sess,
+	}
+
+	httpHdl.hdlCommandCache.Store(
+		msg.msgID, &httpMsgWriter{result: make(chan *Message, 1)},
+	)
+
+	if err := httpHdl.sendCommand(
+		httpHdl.streamCtx, &msg, time.Second*5,
+	); err != nil {
+		httpHdl.hdlCommandCache.Delete(msg.msgID)
+		slog.Warn(
+			"send cancel command failed",
+			slog.Any("error", err),
+			slog.Uint64("msg_id", id),
+		)
+	}
+}
+
+func (httpHdl *CtlHttpHandler) Start() {
+	httpHdl.baseStart()
+
+	go func() {
+		if err := httpHdl.server.Serve(
+			httpHdl.listen,
+		); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error(
+				"serve http ctl failed",
+				slog.Any("error", err),
+			)
+		}
+	}()
+}
+
+func (httpHdl *CtlHttpHandler) Release() {
+	// 先结束 SSE / WebSocket 长连接，避免 Shutdown 等待超时
+	httpHdl.streamCancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	if err := httpHdl.server.Shutdown(ctx); err != nil {
+		slog.Error(
+			"shutdown http server failed",
+			slog.Any("error", err),
+		)
+	}
+
+	httpHdl.ctlBaseHandler.baseRelease()
+}
+
+func NewCtlHttpHandler(conn string) (*CtlHttpHandler, error) {
+	addr, opts, err := splitConnOptions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	listen, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	hdl := CtlHttpHandler{
+		listen:  listen,
+		timeout: time.Second * 30,
+		token:   opts.Get("token"),
+	}
+	if origins := opts.Get("origin"); origins != "" {
+		hdl.origins = strings.Split(origins, ",")
+	}
+	if err = hdl.parseBaseOptions(opts); err != nil {
+		listen.Close()
+		return nil, err
+	}
+	hdl.hdlName = fmt.Sprint("ctl_http_", addr)
+	hdl.connName = fmt.Sprint("http://", addr)
+	hdl.streamCtx, hdl.streamCancel = context.WithCancel(context.Background())
+
+	// 未指定令牌时无法区分请求来源，未显式指定角色则默认为 viewer
+	if hdl.token == "" {
+		if opts.Get("role") == "" {
+			hdl.role = RoleViewer
+		}
+
+		slog.Warn(
+			"http ctl handler running without token",
+			slog.String("conn", hdl.connName),
+			slog.String("role", hdl.role.String()),
+		)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /", dashboardHandler())
+	mux.HandleFunc("GET /api/auth", hdl.handleAuth)
+	mux.HandleFunc("POST /api/ticket", hdl.handleTicket)
+	mux.HandleFunc("GET /api/events", hdl.handleSSE)
+	mux.HandleFunc("GET /api/ws", hdl.handleWebSocket)
+	for _, route := range httpRoutes {
+		mux.HandleFunc(
+			route.method+" "+route.path,
+			hdl.handleCommand(route.cmdName),
+		)
+	}
+
+	hdl.server = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+
+	return &hdl, nil
+}
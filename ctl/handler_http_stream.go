@@ -0,0 +1,388 @@
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamEnvelope 为 SSE / WebSocket 使用的消息格式
+// 与 Message 不同，Data 不做 base64 编码，便于浏览器等直接使用
+type streamEnvelope struct {
+	MsgID   uint64
+	MsgType string
+	Topic   Topic `json:",omitempty"`
+	Data    json.RawMessage
+}
+
+func newStreamEnvelope(msg *Message) ([]byte, error) {
+	env := streamEnvelope{
+		MsgID:   msg.msgID,
+		MsgType: msg.msgType.String(),
+		Topic:   msg.topic,
+		Data:    msg.data,
+	}
+
+	if !json.Valid(msg.data) {
+		data, err := json.Marshal(string(msg.data))
+		if err != nil {
+			return nil, err
+		}
+		env.Data = data
+	}
+
+	return json.Marshal(&env)
+}
+
+type sseMsgWriter struct {
+	hdl      *CtlHttpHandler
+	identity string
+	session  *session
+	lock     sync.Mutex
+	closed   bool
+	w        http.ResponseWriter
+	flusher  http.Flusher
+	queue    *sendQueue
+}
+
+func (wr *sseMsgWriter) writeEvent(event, id string, data []byte) error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	if wr.closed {
+		return errors.New("sse stream closed")
+	}
+
+	var err error
+	if event != "" {
+		_, err = fmt.Fprintf(wr.w, "event: %s\n", event)
+	}
+	if err == nil && id != "" {
+		_, err = fmt.Fprintf(wr.w, "id: %s\n", id)
+	}
+	if err == nil {
+		_, err = fmt.Fprintf(wr.w, "data: %s\n\n", data)
+	}
+	if err != nil {
+		return err
+	}
+
+	wr.flusher.Flush()
+	return nil
+}
+
+func (wr *sseMsgWriter) getSession() *session {
+	return wr.session
+}
+
+func (wr *sseMsgWriter) Close() error {
+	wr.queue.shutdown()
+	return nil
+}
+
+func (wr *sseMsgWriter) queueStats() QueueStat {
+	return wr.queue.stats()
+}
+
+func (wr *sseMsgWriter) kick(reason string) error {
+	return wr.queue.kick(reason)
+}
+
+func (wr *sseMsgWriter) Write(msg *Message) error {
+	return wr.queue.push(msg)
+}
+
+func (wr *sseMsgWriter) write(msg *Message) error {
+	data, err := newStreamEnvelope(msg)
+	if err != nil {
+		return err
+	}
+
+	return wr.writeEvent(
+		strings.ToLower(msg.msgType.String()),
+		strconv.FormatUint(msg.msgID, 10),
+		data,
+	)
+}
+
+func (httpHdl *CtlHttpHandler) handleSSE(w http.ResponseWriter, r *http.Request) {
+	if !httpHdl.allowedOrigin(r) {
+		writeHttpError(w, http.StatusForbidden, "", ErrHttpCrossOrigin)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeHttpError(
+			w, http.StatusInternalServerError, "",
+			errors.New("streaming not supported"),
+		)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// 发送队列溢出时通过 cancel 结束推送
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// 经 Authorization 头或 ticket 查询参数（EventSource）校验，未通过时至多为 viewer
+	identity := fmt.Sprintf("sse://%s#%d", r.RemoteAddr, httpHdl.cmdSeq.Add(1))
+	wr := &sseMsgWriter{
+		hdl:      httpHdl,
+		identity: identity,
+		session:  httpHdl.requestSession(identity, httpHdl.streamAuthorized(r)),
+		w:        w,
+		flusher:  flusher,
+	}
+	wr.queue = newSendQueue(
+		identity, httpHdl.queue, wr.write,
+		func() error { cancel(); return nil },
+	)
+
+	slog.Info(
+		"sse ctl client connected",
+		slog.String("remote", identity),
+	)
+
+	httpHdl.addConn(identity, wr)
+	defer func() {
+		wr.queue.stop()
+
+		wr.lock.Lock()
+		wr.closed = true
+		wr.lock.Unlock()
+
+		if _, exist := httpHdl.hdlConnections.Load(identity); exist {
+			httpHdl.delConn(identity)
+		}
+
+		slog.Info(
+			"sse ctl client disconnected",
+			slog.String("remote", identity),
+		)
+	}()
+
+	keepAlive := time.NewTicker(time.Second * 15)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-httpHdl.streamCtx.Done():
+			return
+		case <-keepAlive.C:
+			wr.lock.Lock()
+			_, err := w.Write([]byte(": keepalive\n\n"))
+			if err == nil {
+				flusher.Flush()
+			}
+			wr.lock.Unlock()
+
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+type wsMsgWriter struct {
+	hdl      *CtlHttpHandler
+	identity string
+	ws       *wsConn
+	session  *session
+	pending  sync.Map
+	queue    *sendQueue
+}
+
+func (wr *wsMsgWriter) getSession() *session {
+	return wr.session
+}
+
+func (wr *wsMsgWriter) Close() error {
+	wr.queue.shutdown()
+	return nil
+}
+
+func (wr *wsMsgWriter) queueStats() QueueStat {
+	return wr.queue.stats()
+}
+
+func (wr *wsMsgWriter) kick(reason string) error {
+	return wr.queue.kick(reason)
+}
+
+func (wr *wsMsgWriter) Write(msg *Message) error {
+	return wr.queue.push(msg)
+}
+
+func (wr *wsMsgWriter) write(msg *Message) error {
+	rsp := *msg
+
+	// 还原客户端自身的消息ID
+	switch rsp.msgType {
+	case MsgResult:
+		if clientID, exist := wr.pending.LoadAndDelete(rsp.msgID); exist {
+			rsp.msgID = clientID.(uint64)
+		}
+	case MsgProgress:
+		if clientID, exist := wr.pending.Load(rsp.msgID); exist {
+			rsp.msgID = clientID.(uint64)
+		}
+	}
+
+	data, err := newStreamEnvelope(&rsp)
+	if err != nil {
+		return err
+	}
+
+	return wr.ws.WriteMessage(wsOpText, data)
+}
+
+func (httpHdl *CtlHttpHandler) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// 浏览器不限制跨站 WebSocket 连接，须校验 Origin 避免其他页面借用户浏览器执行命令
+	if !httpHdl.allowedOrigin(r) {
+		writeHttpError(w, http.StatusForbidden, "", ErrHttpCrossOrigin)
+		return
+	}
+
+	ws, err := upgradeWebSocket(w, r)
+	if err != nil {
+		writeHttpError(w, http.StatusBadRequest, "", err)
+		return
+	}
+
+	closeOnce := sync.OnceFunc(func() { ws.Close() })
+
+	identity := fmt.Sprintf("ws://%s#%d", r.RemoteAddr, httpHdl.cmdSeq.Add(1))
+	wr := &wsMsgWriter{
+		hdl:      httpHdl,
+		identity: identity,
+		ws:       ws,
+		session:  httpHdl.requestSession(identity, httpHdl.streamAuthorized(r)),
+	}
+	wr.queue = newSendQueue(
+		identity, httpHdl.queue, wr.write,
+		func() error { closeOnce(); return nil },
+	)
+
+	slog.Info(
+		"websocket ctl client connected",
+		slog.String("remote", identity),
+	)
+
+	httpHdl.addConn(identity, wr)
+
+	defer func() {
+		wr.queue.stop()
+
+		if _, exist := httpHdl.hdlConnections.Load(identity); exist {
+			httpHdl.delConn(identity)
+		}
+
+		closeOnce()
+
+		slog.Info(
+			"websocket ctl client disconnected",
+			slog.String("remote", identity),
+		)
+	}()
+
+	go func() {
+		<-httpHdl.streamCtx.Done()
+		closeOnce()
+	}()
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if !errors.Is(err, ErrWsClosed) {
+				slog.Error(
+					"read websocket message failed",
+					slog.Any("error", err),
+					slog.String("remote", identity),
+				)
+			}
+			return
+		}
+
+		var env struct {
+			MsgID uint64
+			Data  json.RawMessage
+		}
+		if err := json.Unmarshal(data, &env); err != nil {
+			slog.Error(
+				"unmarshal websocket message failed",
+				slog.Any("error", err),
+			)
+			continue
+		}
+
+		var cmd Command
+		if err := json.Unmarshal(env.Data, &cmd); err != nil || cmd.Name == "" {
+			slog.Error(
+				"invalid websocket command",
+				slog.Any("error", err),
+				slog.String("data", string(env.Data)),
+			)
+			continue
+		}
+
+		if err := wr.session.authorize(cmd.Name); err != nil {
+			data, _ := json.Marshal(&Result{
+				Rtn:     RtnDenied,
+				Message: err.Error(),
+				CmdName: cmd.Name,
+			})
+
+			// 与正常命令相同分配服务端消息ID，避免客户端ID 与其他命令的 pending 冲突
+			id := httpHdl.cmdSeq.Add(1)
+			wr.pending.Store(id, env.MsgID)
+
+			if err := wr.Write(&Message{
+				msgID:   id,
+				msgType: MsgResult,
+				data:    data,
+			}); err != nil {
+				wr.pending.Delete(id)
+				return
+			}
+
+			continue
+		}
+
+		msg := Message{
+			msgID:    httpHdl.cmdSeq.Add(1),
+			msgType:  MsgCommand,
+			data:     env.Data,
+			session:  wr.session,
+			clientID: env.MsgID,
+		}
+
+		wr.pending.Store(msg.msgID, env.MsgID)
+		httpHdl.hdlCommandCache.Store(msg.msgID, wr)
+
+		if err := httpHdl.sendCommand(
+			r.Context(), &msg, time.Second*5,
+		); err != nil {
+			httpHdl.hdlCommandCache.Delete(msg.msgID)
+			wr.pending.Delete(msg.msgID)
+			slog.Warn(
+				"send message from WebSocket to ctl server failed",
+				slog.Any("error", err),
+			)
+		}
+	}
+}
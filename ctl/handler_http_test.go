@@ -0,0 +1,536 @@
+package ctl
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+// newHttpTestServer 启动以 token 校验的 HTTP 控制服务，返回服务地址
+func newHttpTestServer(t *testing.T, token string) string {
+	t.Helper()
+
+	_, addr := newHttpTestHandler(t, "token="+token)
+
+	return addr
+}
+
+// newHttpTestHandler 以连接参数 opts 启动 HTTP 控制服务，返回 Handler 及服务地址
+func newHttpTestHandler(t *testing.T, opts string) (*CtlHttpHandler, string) {
+	t.Helper()
+
+	svr, err := NewCtlServer(
+		t.Context(),
+		(&CtlSvrHdlConfig{}).Http("127.0.0.1:0?"+opts),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	go svr.runForever()
+	t.Cleanup(svr.cancel)
+
+	hdl := svr.handlers[0].(*CtlHttpHandler)
+
+	return hdl, "http://" + hdl.listen.Addr().String()
+}
+
+// readWsResult 读取下一条 Result 消息，跳过其他类型的消息
+func readWsResult(t *testing.T, rd *bufio.Reader) (uint64, *Result) {
+	t.Helper()
+
+	for {
+		op, data := readServerFrame(t, rd)
+		if op != wsOpText {
+			t.Fatalf("unexpected opcode: %d", op)
+		}
+
+		var env streamEnvelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			t.Fatal(err)
+		}
+
+		if env.MsgType != MsgResult.String() {
+			continue
+		}
+
+		var result Result
+		if err := json.Unmarshal(env.Data, &result); err != nil {
+			t.Fatal(err)
+		}
+
+		return env.MsgID, &result
+	}
+}
+
+func TestWebSocketDenied(t *testing.T) {
+	release := make(chan struct{})
+
+	if err := RegisterCommand(CommandSpec{
+		Name: "block", Role: RoleViewer, ClientFree: true, Concurrent: true,
+		Handler: func(context.Context, *CtlServer, *Command, *Result) error {
+			<-release
+			return nil
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnRegisterCommand("block")
+
+	addr := newHttpTestServer(t, "secret")
+
+	// 未携带 token 的连接至多为 viewer 角色
+	conn, rd := dialWebSocket(t, addr, "/api/ws")
+	defer conn.Close()
+
+	writeMaskedFrame(t, conn, wsOpText, []byte(
+		`{"MsgID":100,"Data":{"Name":"block"}}`,
+	))
+
+	// 客户端ID 与执行中命令的服务端ID 相同时，拒绝结果不影响执行中命令的结果
+	writeMaskedFrame(t, conn, wsOpText, []byte(
+		`{"MsgID":2,"Data":{"Name":"stop"}}`,
+	))
+
+	id, result := readWsResult(t, rd)
+	if id != 2 || result.CmdName != "stop" || result.Rtn != RtnDenied {
+		t.Fatalf("denied result mismatch: %d, %+v", id, result)
+	}
+
+	close(release)
+
+	if id, result = readWsResult(t, rd); id != 100 ||
+		result.CmdName != "block" || result.Rtn != 0 {
+		t.Fatalf("command result mismatch: %d, %+v", id, result)
+	}
+}
+
+// httpDo 发送 REST 请求，返回状态码及响应内容
+func httpDo(
+	t *testing.T, method, url, token string, body string,
+) (int, []byte) {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(
+		t.Context(), method, url, strings.NewReader(body),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rsp.Body.Close()
+
+	data, err := io.ReadAll(rsp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rsp.StatusCode, data
+}
+
+// httpCall 发送 REST 命令请求，返回状态码及响应中的 Result
+func httpCall(
+	t *testing.T, method, url, token string, body string,
+) (int, *Result) {
+	t.Helper()
+
+	status, data := httpDo(t, method, url, token, body)
+
+	var result Result
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("invalid result %s: %v", data, err)
+	}
+
+	return status, &result
+}
+
+func TestHttpAuth(t *testing.T) {
+	addr := newHttpTestServer(t, "secret")
+
+	for _, c := range []struct {
+		url, token string
+		status     int
+	}{
+		{"/api/auth", "", http.StatusUnauthorized},
+		{"/api/auth", "wrong", http.StatusUnauthorized},
+		{"/api/auth", "secret", http.StatusOK},
+		// 令牌不可经查询参数传递
+		{"/api/auth?token=secret", "", http.StatusUnauthorized},
+	} {
+		if status, _ := httpDo(
+			t, http.MethodGet, addr+c.url, c.token, "",
+		); status != c.status {
+			t.Fatalf("%s with token %q: status %d, expect %d", c.url, c.token, status, c.status)
+		}
+	}
+}
+
+func TestHttpViewerCapped(t *testing.T) {
+	addr := newHttpTestServer(t, "secret")
+
+	// 未通过校验的请求仅可执行 viewer 命令
+	status, result := httpCall(t, http.MethodGet, addr+"/api/schema?name=stop", "", "")
+	if status != http.StatusOK || result.Rtn != 0 {
+		t.Fatalf("viewer command failed: %d, %+v", status, result)
+	}
+
+	for _, token := range []string{"", "wrong"} {
+		status, result = httpCall(t, http.MethodGet, addr+"/api/sessions", token, "")
+		if status != http.StatusUnauthorized || result.CmdName != "sessions" {
+			t.Fatalf("admin command not denied: %d, %+v", status, result)
+		}
+	}
+
+	status, result = httpCall(t, http.MethodGet, addr+"/api/sessions", "secret", "")
+	if status != http.StatusOK || result.Rtn != 0 {
+		t.Fatalf("authorized admin command failed: %d, %+v", status, result)
+	}
+}
+
+func TestHttpCommandRoundTrip(t *testing.T) {
+	if err := RegisterCommand(CommandSpec{
+		Name: "greet", ClientFree: true, Concurrent: true,
+		Args: []ArgSpec{{Name: "name", Type: ArgString, Required: true}},
+		Handler: func(_ context.Context, _ *CtlServer, cmd *Command, result *Result) error {
+			result.Message = "hello " + cmd.KwArgs["name"]
+			result.Values[VKeyPluginName] = cmd.KwArgs["name"]
+			return nil
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnRegisterCommand("greet")
+
+	addr := newHttpTestServer(t, "secret")
+
+	status, result := httpCall(
+		t, http.MethodPost, addr+"/api/commands/greet", "secret", `{"name":"ops"}`,
+	)
+	if status != http.StatusOK || result.CmdName != "greet" ||
+		result.Message != "hello ops" {
+		t.Fatalf("command result mismatch: %d, %+v", status, result)
+	}
+
+	if name, _, err := GetResultValue[string](
+		result, VKeyPluginName,
+	); err != nil || name != "ops" {
+		t.Fatalf("command result value mismatch: %s, %v", name, err)
+	}
+
+	// 参数校验失败以 400 返回 Result
+	if status, result = httpCall(
+		t, http.MethodPost, addr+"/api/commands/greet", "secret", "",
+	); status != http.StatusBadRequest || result.Rtn == 0 {
+		t.Fatalf("invalid command not rejected: %d, %+v", status, result)
+	}
+
+	// 需要 LatencyClient 的命令在客户端未运行时失败
+	if status, result = httpCall(
+		t, http.MethodGet, addr+"/api/state", "secret", "",
+	); status != http.StatusBadRequest || result.CmdName != "state" {
+		t.Fatalf("state without client not failed: %d, %+v", status, result)
+	}
+}
+
+func TestHttpEventsTicket(t *testing.T) {
+	hdl, addr := newHttpTestHandler(t, "token=secret")
+
+	issue := func(token string) (int, string) {
+		status, data := httpDo(t, http.MethodPost, addr+"/api/ticket", token, "")
+
+		var rsp struct{ Ticket string }
+		if status == http.StatusOK {
+			if err := json.Unmarshal(data, &rsp); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return status, rsp.Ticket
+	}
+
+	if status, _ := issue(""); status != http.StatusUnauthorized {
+		t.Fatalf("ticket issued without token: %d", status)
+	}
+
+	_, ticket := issue("secret")
+	_, expired := issue("secret")
+	hdl.tickets.Store(expired, time.Now().Add(-time.Second))
+
+	for _, c := range []struct {
+		query string
+		role  Role
+	}{
+		{"", RoleViewer},
+		{"?token=secret", RoleViewer},
+		{"?ticket=wrong", RoleViewer},
+		{"?ticket=" + expired, RoleViewer},
+		// EventSource 以一次性票据代替令牌
+		{"?ticket=" + ticket, RoleAdmin},
+		{"?ticket=" + ticket, RoleViewer},
+	} {
+		ctx, cancel := context.WithCancel(t.Context())
+
+		req, err := http.NewRequestWithContext(
+			ctx, http.MethodGet, addr+"/api/events"+c.query, nil,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rsp.StatusCode != http.StatusOK {
+			t.Fatalf("sse request failed: %s", rsp.Status)
+		}
+
+		var sess *SessionInfo
+		for range 100 {
+			if sessions := hdl.Sessions(); len(sessions) == 1 {
+				sess = &sessions[0]
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+
+		if sess == nil || sess.Role != c.role {
+			t.Fatalf("sse session%s mismatch: %+v", c.query, sess)
+		}
+
+		if _, exist := sess.Subscriptions[TopicState]; !exist {
+			t.Fatalf("sse session not subscribed to state: %+v", sess)
+		}
+
+		cancel()
+		rsp.Body.Close()
+
+		for range 100 {
+			if len(hdl.Sessions()) == 0 {
+				break
+			}
+			time.Sleep(time.Millisecond * 10)
+		}
+	}
+
+	// 其他来源的页面无法订阅推送
+	req, err := http.NewRequestWithContext(
+		t.Context(), http.MethodGet, addr+"/api/events", nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsp.Body.Close()
+
+	if rsp.StatusCode != http.StatusForbidden {
+		t.Fatalf("cross origin sse not rejected: %s", rsp.Status)
+	}
+}
+
+func TestHttpNoTokenRole(t *testing.T) {
+	addr := newHttpTestServer(t, "")
+
+	// 未指定令牌时连接默认角色为 viewer
+	status, result := httpCall(t, http.MethodGet, addr+"/api/sessions", "", "")
+	if status != http.StatusForbidden || result.Rtn == 0 {
+		t.Fatalf("admin command not denied without token: %d, %+v", status, result)
+	}
+
+	// 显式指定的角色不受影响
+	hdl, err := NewCtlHttpHandler("127.0.0.1:0?role=operator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer hdl.listen.Close()
+
+	if hdl.role != RoleOperator {
+		t.Fatalf("explicit role overridden: %s", hdl.role)
+	}
+}
+
+func TestHttpContentType(t *testing.T) {
+	addr := newHttpTestServer(t, "secret")
+
+	for _, contentType := range []string{
+		"", "application/x-www-form-urlencoded", "text/plain",
+	} {
+		req, err := http.NewRequestWithContext(
+			t.Context(), http.MethodPost, addr+"/api/suspend",
+			strings.NewReader("a=b"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+
+		if rsp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Fatalf("content type %q not rejected: %s", contentType, rsp.Status)
+		}
+	}
+}
+
+func TestHttpBodyTooLarge(t *testing.T) {
+	addr := newHttpTestServer(t, "secret")
+
+	body := `{"config":"` + strings.Repeat("x", httpMaxBodyBytes) + `"}`
+	if status, _ := httpDo(
+		t, http.MethodPut, addr+"/api/config", "secret", body,
+	); status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body not rejected: %d", status)
+	}
+
+	// 未超限的请求体照常处理
+	if status, _ := httpDo(
+		t, http.MethodPost, addr+"/api/suspend", "secret", `{}`,
+	); status == http.StatusRequestEntityTooLarge {
+		t.Fatalf("normal body rejected: %d", status)
+	}
+}
+
+func TestHttpAbortCancel(t *testing.T) {
+	cancelled := make(chan error, 1)
+	if err := RegisterCommand(CommandSpec{
+		Name: "wait", ClientFree: true, Concurrent: true,
+		Handler: func(ctx context.Context, _ *CtlServer, _ *Command, _ *Result) error {
+			<-ctx.Done()
+			cancelled <- context.Cause(ctx)
+			return context.Cause(ctx)
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnRegisterCommand("wait")
+
+	svr, err := NewCtlServer(
+		t.Context(),
+		(&CtlSvrHdlConfig{}).Http("127.0.0.1:0?token=secret"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	go svr.runForever()
+	defer svr.cancel()
+
+	addr := "http://" + svr.handlers[0].(*CtlHttpHandler).listen.Addr().String()
+
+	if status, _ := httpDo(
+		t, http.MethodPost, addr+"/api/commands/cancel?id=1", "secret", "",
+	); status != http.StatusBadRequest {
+		t.Fatalf("rest cancel not rejected: %d", status)
+	}
+
+	// 中断请求即取消服务端执行中的命令
+	ctx, cancel := context.WithTimeout(t.Context(), time.Millisecond*200)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(
+		ctx, http.MethodPost, addr+"/api/commands/wait", nil,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	req.Header.Set("Content-Type", "application/json")
+
+	if rsp, err := http.DefaultClient.Do(req); err == nil {
+		rsp.Body.Close()
+		t.Fatalf("wait command returned: %s", rsp.Status)
+	}
+
+	select {
+	case cause := <-cancelled:
+		if !errors.Is(cause, ErrCommandCancelled) {
+			t.Fatalf("command not cancelled: %v", cause)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait command cancel timeout")
+	}
+
+	// 等待被取消的命令及 cancel 命令写入结果后再结束服务
+	for deadline := time.Now().Add(time.Second * 5); ; {
+		inflight := 0
+		svr.inflight.Range(func(_, _ any) bool { inflight++; return true })
+		if inflight == 0 {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("%d commands still in flight", inflight)
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	_, addr := newHttpTestHandler(t, "token=secret&origin=dash.example.com")
+
+	for _, c := range []struct {
+		origin string
+		status int
+	}{
+		{"", http.StatusSwitchingProtocols},
+		{addr, http.StatusSwitchingProtocols},
+		{"https://dash.example.com", http.StatusSwitchingProtocols},
+		{"https://evil.example.com", http.StatusForbidden},
+		{"null", http.StatusForbidden},
+	} {
+		req, err := http.NewRequestWithContext(
+			t.Context(), http.MethodGet, addr+"/api/ws", nil,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+
+		rsp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rsp.Body.Close()
+
+		if rsp.StatusCode != c.status {
+			t.Fatalf("origin %q: status %s, expect %d", c.origin, rsp.Status, c.status)
+		}
+	}
+}
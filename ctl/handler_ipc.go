@@ -1,6 +1,7 @@
 package ctl
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
@@ -15,6 +16,7 @@ type CtlIpcHandler struct {
 
 	server     *ipc.Server
 	svrRunning atomic.Bool
+	session    *session
 }
 
 func (ipcHdl *CtlIpcHandler) Write(msg *Message) error {
@@ -37,6 +39,10 @@ func (ipcHdl *CtlIpcHandler) Write(msg *Message) error {
 	}
 }
 
+func (ipcHdl *CtlIpcHandler) getSession() *session {
+	return ipcHdl.session
+}
+
 func (ipcHdl *CtlIpcHandler) Start() {
 	ipcHdl.baseStart()
 
@@ -67,11 +73,16 @@ func (ipcHdl *CtlIpcHandler) Start() {
 						slog.Any("error", err),
 					)
 				} else {
+					msg.session = ipcHdl.session
 					ipcHdl.hdlCommandCache.Store(msg.msgID, ipcHdl)
-					select {
-					case ipcHdl.hdlCommands <- &msg:
-					case <-time.After(time.Second * 5):
-						slog.Warn("send message from IPC to ctl server timeout")
+					if err := ipcHdl.sendCommand(
+						context.Background(), &msg, time.Second*5,
+					); err != nil {
+						ipcHdl.hdlCommandCache.Delete(msg.msgID)
+						slog.Warn(
+							"send message from IPC to ctl server failed",
+							slog.Any("error", err),
+						)
 					}
 				}
 			} else {
@@ -88,17 +99,24 @@ func (ipcHdl *CtlIpcHandler) Release() {
 	ipcHdl.ctlBaseHandler.baseRelease()
 }
 
-func NewIpcCtlHandler(name string) (*CtlIpcHandler, error) {
-	svr, err := ipc.StartServer(name, nil)
+func NewIpcCtlHandler(conn string) (*CtlIpcHandler, error) {
+	name, opts, err := splitConnOptions(conn)
 	if err != nil {
 		return nil, err
 	}
 
-	hdl := CtlIpcHandler{
-		server: svr,
+	hdl := CtlIpcHandler{}
+	if err = hdl.parseBaseOptions(opts); err != nil {
+		return nil, err
 	}
+
+	if hdl.server, err = ipc.StartServer(name, nil); err != nil {
+		return nil, err
+	}
+
 	hdl.hdlName = fmt.Sprint("ctl_ipc_", name)
 	hdl.connName = fmt.Sprint("ipc://", name)
+	hdl.session = hdl.newSession("ipc client", "")
 	hdl.svrRunning.Store(true)
 
 	return &hdl, nil
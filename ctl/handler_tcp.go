@@ -1,96 +1,381 @@
 package ctl
 
 import (
-	"bufio"
-	"encoding/binary"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const ctlAuthTimeout = time.Second * 10
+
 type CtlTcpHandler struct {
 	ctlBaseHandler
 
-	listen net.Listener
+	listen  net.Listener
+	auth    *ctlAuthenticator
+	connSeq atomic.Uint32
+	cmdSeq  atomic.Uint64
+	maxSize int
 }
 
 type tcpMsgWriter struct {
 	hdl      *CtlTcpHandler
 	conn     net.Conn
+	remote   string
 	identity string
-	mask     uint64
+	session  *session
+	// pending 服务端消息ID 至客户端消息ID 的映射，结果返回时还原客户端自身的消息ID
+	pending sync.Map
+	seen    atomic.Int64
+	codec   *frameCodec
+	lock    sync.Mutex
+	queue   *sendQueue
+}
+
+func (wr *tcpMsgWriter) lastSeen() time.Time {
+	return time.Unix(0, wr.seen.Load())
+}
+
+func (wr *tcpMsgWriter) getSession() *session {
+	return wr.session
+}
+
+func (wr *tcpMsgWriter) Close() error {
+	return wr.conn.Close()
+}
+
+func (wr *tcpMsgWriter) queueStats() QueueStat {
+	return wr.queue.stats()
+}
+
+func (wr *tcpMsgWriter) kick(reason string) error {
+	return wr.queue.kick(reason)
 }
 
 func (wr *tcpMsgWriter) Write(msg *Message) error {
-	if msg.msgID > 0 {
-		msg.msgID ^= wr.mask
+	return wr.queue.push(msg)
+}
+
+func (wr *tcpMsgWriter) write(msg *Message) error {
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	switch msg.msgType {
+	case MsgResult:
+		if clientID, exist := wr.pending.LoadAndDelete(msg.msgID); exist {
+			rsp := *msg
+			rsp.msgID = clientID.(uint64)
+			msg = &rsp
+		}
+	case MsgProgress:
+		if clientID, exist := wr.pending.Load(msg.msgID); exist {
+			rsp := *msg
+			rsp.msgID = clientID.(uint64)
+			msg = &rsp
+		}
+	}
+
+	frame, err := wr.codec.encode(msg)
+	if err != nil {
+		return err
 	}
 
-	data, err := json.Marshal(msg)
+	_, err = wr.conn.Write(frame)
+	return err
+}
+
+// writeHello 以握手前的分帧方式返回协商结果，之后的消息均使用协商的分帧方式
+func (wr *tcpMsgWriter) writeHello(hello *Hello) error {
+	msg, err := newHelloMessage(hello)
 	if err != nil {
 		return err
 	}
 
-	buff := make([]byte, len(data)+1)
-	buff[copy(buff, data)] = '\n'
+	wr.lock.Lock()
+	defer wr.lock.Unlock()
+
+	frame, err := wr.codec.encode(msg)
+	if err != nil {
+		return err
+	}
 
-	if _, err = wr.conn.Write(buff); err != nil {
-		wr.hdl.delConn(wr.identity)
+	if _, err = wr.conn.Write(frame); err != nil {
 		return err
 	}
 
+	wr.codec.setFraming(hello.Framing, hello.MaxSize)
+
 	return nil
 }
 
+// writeAuth 认证消息不经过发送队列，确保认证失败断开连接前应答已写入
+func (wr *tcpMsgWriter) writeAuth(auth *authData) error {
+	msg, err := newAuthMessage(auth)
+	if err != nil {
+		return err
+	}
+
+	return wr.write(msg)
+}
+
+// authenticate 处理连接认证握手，认证完成前仅接受 MsgAuth 消息
+func (tcpHdl *CtlTcpHandler) authenticate(
+	wr *tcpMsgWriter, msg *Message, nonce *string,
+) (bool, error) {
+	if msg.GetType() != MsgAuth {
+		result := Result{
+			Rtn:     1,
+			Message: ErrAuthRequired.Error(),
+		}
+		if cmd, err := msg.GetCommand(); err == nil && cmd != nil {
+			result.CmdName = cmd.Name
+		}
+
+		data, err := json.Marshal(&result)
+		if err != nil {
+			return false, err
+		}
+
+		if err = wr.write(&Message{
+			msgID:   msg.msgID,
+			msgType: MsgResult,
+			data:    data,
+		}); err != nil {
+			return false, err
+		}
+
+		return false, ErrAuthRequired
+	}
+
+	auth, err := msg.getAuth()
+	if err != nil || auth == nil || auth.Identity == "" {
+		wr.writeAuth(&authData{Rtn: 1, Message: ErrAuthFailed.Error()})
+		return false, fmt.Errorf("%w: invalid auth data", ErrAuthFailed)
+	}
+
+	if auth.Signature == "" {
+		if *nonce, err = newNonce(); err != nil {
+			return false, err
+		}
+
+		return false, wr.writeAuth(&authData{Nonce: *nonce})
+	}
+
+	if !tcpHdl.auth.verify(auth.Identity, *nonce, auth.Signature) {
+		wr.writeAuth(&authData{Rtn: 1, Message: ErrAuthFailed.Error()})
+		return false, fmt.Errorf(
+			"%w: invalid signature for %s", ErrAuthFailed, auth.Identity,
+		)
+	}
+
+	wr.identity = auth.Identity
+
+	return true, wr.writeAuth(&authData{
+		Identity: auth.Identity,
+		Message:  "authenticated",
+	})
+}
+
 func (tcpHdl *CtlTcpHandler) handleConn(conn net.Conn) {
-	remote := conn.RemoteAddr().(*net.TCPAddr)
-	remoteIdt := remote.String()
-	remoteIP := remote.IP.To4()
-	remotePort := uint64(remote.Port)
+	var (
+		remoteIdt    string
+		peerIdentity string
+	)
+
+	switch remote := conn.RemoteAddr().(type) {
+	case *net.TCPAddr:
+		remoteIdt = remote.String()
+	default:
+		// unix domain socket 客户端无远端地址，以连接序号区分
+		seq := tcpHdl.connSeq.Add(1)
+
+		remoteIdt = fmt.Sprint(tcpHdl.connName, "#", seq)
+	}
+
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		tlsConn.SetDeadline(time.Now().Add(ctlAuthTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			slog.Error(
+				"tls ctl client handshake failed",
+				slog.Any("error", err),
+				slog.String("remote", remoteIdt),
+			)
+			conn.Close()
+			return
+		}
+		tlsConn.SetDeadline(time.Time{})
 
-	mask := uint64(binary.LittleEndian.Uint32(remoteIP))<<32 |
-		remotePort<<16
+		peerIdentity = tlsPeerIdentity(tlsConn)
+	} else if cred, err := peerCredential(conn); err == nil {
+		peerIdentity = cred.identity()
+
+		slog.Info(
+			"unix ctl client peer credential",
+			slog.String("remote", remoteIdt),
+			slog.Uint64("uid", uint64(cred.uid)),
+			slog.Uint64("gid", uint64(cred.gid)),
+			slog.Int("pid", int(cred.pid)),
+		)
+	}
 
 	slog.Info(
 		"tcp ctl client connected",
 		slog.String("remote", remoteIdt),
+		slog.String("identity", peerIdentity),
 	)
 
-	rd := bufio.NewScanner(conn)
+	codec := newFrameCodec(conn, tcpHdl.maxSize)
 	wr := &tcpMsgWriter{
 		hdl:      tcpHdl,
 		conn:     conn,
-		identity: remoteIdt,
-		mask:     mask,
+		remote:   remoteIdt,
+		identity: peerIdentity,
+		codec:    codec,
 	}
+	wr.queue = newSendQueue(remoteIdt, tcpHdl.queue, wr.write, wr.Close)
+	wr.seen.Store(time.Now().UnixNano())
 
-	tcpHdl.addConn(remoteIdt, wr)
+	var (
+		// 已校验的客户端证书或对端进程凭证即视为认证通过
+		authed = tcpHdl.auth == nil || peerIdentity != ""
+		nonce  string
+		// 仅连接的首条消息可为握手消息
+		first = true
+	)
+
+	if authed {
+		wr.session = tcpHdl.newSession(remoteIdt, wr.identity)
+		tcpHdl.addConn(remoteIdt, wr)
+	} else {
+		conn.SetReadDeadline(time.Now().Add(ctlAuthTimeout))
+	}
 
 	defer func() {
-		tcpHdl.delConn(remoteIdt)
+		if authed {
+			tcpHdl.delConn(remoteIdt)
+		}
+
+		wr.queue.stop()
+
+		// 连接断开后未返回的结果不再投递
+		wr.pending.Range(func(key, _ any) bool {
+			tcpHdl.hdlCommandCache.Delete(key)
+			return true
+		})
 
 		conn.Close()
 	}()
 
-	for rd.Scan() {
-		var msg Message
-		if err := json.Unmarshal(rd.Bytes(), &msg); err != nil {
+	for {
+		msg, err := codec.readMsg()
+		if errors.Is(err, ErrInvalidMsgData) {
 			slog.Error(
 				"unmarshal tcp message failed",
 				slog.Any("error", err),
 			)
-		} else {
-			if msg.msgID > 0 {
-				msg.msgID = (msg.msgID & 0x00000000FFFFFFFF) | mask
+			continue
+		} else if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Error(
+					"read tcp message failed",
+					slog.Any("error", err),
+					slog.String("remote", remoteIdt),
+				)
+			}
+			break
+		}
+
+		wr.seen.Store(time.Now().UnixNano())
+
+		if msg.GetType() == MsgHello && first {
+			first = false
+
+			if err := tcpHdl.handshake(wr, msg); err != nil {
+				slog.Error(
+					"tcp ctl client handshake failed",
+					slog.Any("error", err),
+					slog.String("remote", remoteIdt),
+				)
+				return
+			}
+
+			continue
+		}
+		first = false
+
+		if msg.GetType() == MsgPing {
+			if err := wr.Write(newPongMessage(msg)); err != nil {
+				slog.Error(
+					"write pong msg failed",
+					slog.Any("error", err),
+					slog.String("remote", remoteIdt),
+				)
+			}
+		} else if msg.GetType() == MsgPong {
+			// 仅用于刷新连接活跃时间
+			continue
+		} else if !authed {
+			var err error
+			if authed, err = tcpHdl.authenticate(wr, msg, &nonce); err != nil {
+				slog.Error(
+					"tcp ctl client authenticate failed",
+					slog.Any("error", err),
+					slog.String("remote", remoteIdt),
+				)
+				return
+			}
+
+			if authed {
+				conn.SetReadDeadline(time.Time{})
+				wr.session = tcpHdl.newSession(remoteIdt, wr.identity)
+				tcpHdl.addConn(remoteIdt, wr)
+
+				slog.Info(
+					"tcp ctl client authenticated",
+					slog.String("remote", remoteIdt),
+					slog.String("identity", wr.identity),
+					slog.String("role", wr.session.role.String()),
+				)
+			}
+		} else if msg.GetType() == MsgAuth {
+			// 已由证书或对端进程凭证认证的连接，直接返回认证身份
+			if err := wr.writeAuth(&authData{
+				Identity: wr.identity,
+				Message:  "authenticated",
+			}); err != nil {
+				slog.Error(
+					"write auth msg failed",
+					slog.Any("error", err),
+					slog.String("remote", remoteIdt),
+				)
 			}
+		} else {
+			// 以服务端消息ID 路由结果，避免不同连接的客户端消息ID 冲突
+			clientID := msg.msgID
+			msg.msgID = tcpHdl.cmdSeq.Add(1)
+			msg.clientID = clientID
+			msg.session = wr.session
 
+			wr.pending.Store(msg.msgID, clientID)
 			tcpHdl.hdlCommandCache.Store(msg.msgID, wr)
-			select {
-			case tcpHdl.hdlCommands <- &msg:
-			case <-time.After(time.Second * 5):
-				slog.Warn("send message from IPC to ctl server timeout")
+
+			if err := tcpHdl.sendCommand(
+				context.Background(), msg, time.Second*5,
+			); err != nil {
+				tcpHdl.hdlCommandCache.Delete(msg.msgID)
+				wr.pending.Delete(msg.msgID)
+				slog.Warn(
+					"send message from TCP to ctl server failed",
+					slog.Any("error", err),
+				)
 			}
 		}
 	}
@@ -101,6 +386,49 @@ func (tcpHdl *CtlTcpHandler) handleConn(conn net.Conn) {
 	)
 }
 
+// handshake 交换协议版本及能力，协商分帧方式及消息长度上限，未发送握手消息的旧版客户端保持换行分帧
+func (tcpHdl *CtlTcpHandler) handshake(wr *tcpMsgWriter, msg *Message) error {
+	hello, err := msg.getHello()
+	if err != nil {
+		return err
+	} else if hello == nil {
+		return fmt.Errorf("%w: empty hello msg", ErrInvalidMsgData)
+	}
+
+	rsp := newHello()
+	rsp.Commands = supportedCommands()
+	rsp.Framing = framingLine
+	rsp.MaxSize = tcpHdl.maxSize
+
+	if hello.Framing == framingLength {
+		rsp.Framing = framingLength
+	}
+	if hello.MaxSize > 0 && hello.MaxSize < rsp.MaxSize {
+		rsp.MaxSize = hello.MaxSize
+	}
+
+	// 版本不兼容时仍返回握手应答，由客户端给出明确的错误提示
+	if err = wr.writeHello(rsp); err != nil {
+		return err
+	}
+
+	if err = hello.compatible(); err != nil {
+		return err
+	}
+
+	slog.Info(
+		"tcp ctl client handshake finished",
+		slog.String("remote", wr.remote),
+		slog.Int("version", hello.Version),
+		slog.String("build", hello.Build),
+		slog.Any("capabilities", hello.Capabilities),
+		slog.String("framing", rsp.Framing.String()),
+		slog.Int("max_size", rsp.MaxSize),
+	)
+
+	return nil
+}
+
 func (tcpHdl *CtlTcpHandler) Start() {
 	tcpHdl.baseStart()
 
@@ -131,17 +459,78 @@ func (tcpHdl *CtlTcpHandler) Release() {
 	tcpHdl.ctlBaseHandler.baseRelease()
 }
 
-func NewCtlTcpHandler(conn string) (*CtlTcpHandler, error) {
-	listen, err := net.Listen("tcp4", conn)
+func newCtlTcpHandler(scheme, conn string) (*CtlTcpHandler, error) {
+	addr, opts, err := splitConnOptions(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	hdl := CtlTcpHandler{}
+	if err = hdl.parseBaseOptions(opts); err != nil {
+		return nil, err
+	}
+
+	if hdl.maxSize, err = parseMaxMsgSize(opts.Get("maxsize")); err != nil {
+		return nil, err
+	}
+
+	if keyFile := opts.Get("keys"); keyFile != "" {
+		if hdl.auth, err = loadAuthenticator(keyFile); err != nil {
+			return nil, err
+		}
+
+		// 未单独指定角色文件时，使用密钥文件中的 [roles] 配置
+		if hdl.roles == nil {
+			if hdl.roles, err = loadRoles(keyFile); err != nil {
+				return nil, err
+			}
+		}
+
+		slog.Info(
+			"tcp ctl handler authentication enabled",
+			slog.String("keys", keyFile),
+			slog.Int("count", len(hdl.auth.keys)),
+		)
+	}
+
+	var tlsCfg *tls.Config
+	if scheme == "tls" {
+		if tlsCfg, err = newServerTlsConfig(opts); err != nil {
+			return nil, err
+		}
+
+		slog.Info(
+			"tcp ctl handler tls enabled",
+			slog.Bool("mtls", tlsCfg.ClientAuth == tls.RequireAndVerifyClientCert),
+		)
+	}
+
+	if scheme == "unix" {
+		hdl.listen, err = listenUnix(addr, opts)
+	} else {
+		hdl.listen, err = net.Listen("tcp", addr)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	hdl := CtlTcpHandler{
-		listen: listen,
+	if tlsCfg != nil {
+		hdl.listen = tls.NewListener(hdl.listen, tlsCfg)
 	}
-	hdl.hdlName = fmt.Sprint("ctl_tcp_", conn)
-	hdl.connName = fmt.Sprint("tcp://", conn)
+
+	hdl.hdlName = fmt.Sprint("ctl_", scheme, "_", addr)
+	hdl.connName = fmt.Sprint(scheme, "://", addr)
 
 	return &hdl, nil
 }
+
+func NewCtlTcpHandler(conn string) (*CtlTcpHandler, error) {
+	return newCtlTcpHandler("tcp", conn)
+}
+
+// NewCtlTlsHandler 创建 TLS 加密的 TCP 控制台服务
+// 连接参数：cert, key 服务端证书；ca 客户端 CA（启用 mTLS）；keys 认证密钥文件；
+// role, roles 连接默认角色及身份角色文件；heartbeat 心跳周期
+func NewCtlTlsHandler(conn string) (*CtlTcpHandler, error) {
+	return newCtlTcpHandler("tls", conn)
+}
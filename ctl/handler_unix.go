@@ -0,0 +1,61 @@
+package ctl
+
+import (
+	"errors"
+	"io/fs"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// defaultUnixSockMode unix domain socket 文件默认权限，同组用户可连接
+const defaultUnixSockMode = 0660
+
+// listenUnix 侦听 unix domain socket，启动前清理残留的 socket 文件
+func listenUnix(path string, opts url.Values) (net.Listener, error) {
+	mode := fs.FileMode(defaultUnixSockMode)
+	if v := opts.Get("mode"); v != "" {
+		m, err := strconv.ParseUint(v, 8, 32)
+		if err != nil {
+			return nil, err
+		}
+		mode = fs.FileMode(m)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, errors.New("unix sock path exists and not a socket")
+		}
+
+		// 已有服务侦听时拒绝覆盖
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, errors.New("unix sock already in use")
+		}
+
+		if err = os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listen, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.Chmod(path, mode); err != nil {
+		listen.Close()
+		return nil, err
+	}
+
+	return listen, nil
+}
+
+// NewCtlUnixHandler 创建 unix domain socket 本地控制台服务，支持多客户端同时接入
+// 连接参数：mode socket 文件权限（八进制）；keys 认证密钥文件；role, roles 连接默认角色及身份角色文件；
+// heartbeat 心跳周期
+// Linux 环境下以对端进程用户名（SO_PEERCRED）作为连接身份，无需再进行密钥认证
+func NewCtlUnixHandler(conn string) (*CtlTcpHandler, error) {
+	return newCtlTcpHandler("unix", conn)
+}
@@ -0,0 +1,70 @@
+package ctl
+
+import (
+	"encoding/json"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestUnixHandler(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "ctl.sock")
+
+	hdl, err := NewCtlUnixHandler(sock + "?role=viewer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	identities := make(chan string, 2)
+
+	go func() {
+		for msg := range hdl.Commands() {
+			identities <- msg.session.identity
+
+			cmd, _ := msg.GetCommand()
+			data, _ := json.Marshal(&Result{CmdName: cmd.Name})
+
+			hdl.Publish(&Message{
+				msgID:   msg.msgID,
+				msgType: MsgResult,
+				data:    data,
+			}, time.Second)
+		}
+	}()
+
+	for range 2 {
+		client, err := NewCtlUnixClient(sock)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Init(t.Context(), "test client", func() { go client.recv() })
+		defer client.Release()
+
+		if _, err = client.Call(t.Context(), &Command{Name: "state"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if count := hdl.ConnCount(); count != 2 {
+		t.Fatalf("connection count mismatch: %d", count)
+	}
+
+	if runtime.GOOS != "linux" {
+		return
+	}
+
+	current, err := user.Current()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	for range 2 {
+		if identity := <-identities; identity != current.Username {
+			t.Fatalf("peer identity mismatch: expect %s, got %s", current.Username, identity)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	// ErrLegacyCtlServer 服务端不支持握手，需以换行分帧重新连接
+	ErrLegacyCtlServer = errors.New("legacy ctl server")
+	// ErrIncompatibleProtocol 双方协议版本不兼容，需升级较旧的一端
+	ErrIncompatibleProtocol = errors.New("incompatible ctl protocol")
+	// ErrUnsupportedCommand 服务端未声明支持的命令
+	ErrUnsupportedCommand = errors.New("unsupported command")
+)
+
+const (
+	// ProtocolVersion 当前控制台协议版本，不支持握手的旧版本视为版本 1
+	ProtocolVersion = 2
+	// minProtocolVersion 可兼容的对端最低协议版本
+	minProtocolVersion = 1
+
+	// ctlHelloTimeout 等待握手应答的超时时间，超时视为旧版服务端
+	ctlHelloTimeout = time.Second * 3
+)
+
+// BuildVersion 程序构建版本，由可执行程序在启动时设置，并在握手时发送至对端
+var BuildVersion = "unknown"
+
+// Capability 协议版本之外可选的功能特性
+type Capability string
+
+const (
+	CapFraming   Capability = "framing"
+	CapHeartbeat Capability = "heartbeat"
+	CapTopic     Capability = "topic"
+	CapLog       Capability = "log"
+	CapProgress  Capability = "progress"
+	CapClose     Capability = "close"
+)
+
+var capabilities = []Capability{
+	CapFraming, CapHeartbeat, CapTopic, CapLog, CapProgress, CapClose,
+}
+
+// legacyCommands 不支持握手的旧版服务端所支持的命令
+var legacyCommands = []string{
+	"config", "info", "period", "plugin", "query", "resume",
+	"start", "state", "stop", "suspend", "unplugin",
+}
+
+// Hello 连接建立后的握手内容，双方交换协议版本、构建版本、支持的命令及能力，
+// 客户端提出期望的分帧方式及消息长度上限，服务端返回协商结果
+type Hello struct {
+	Version      int
+	MinVersion   int
+	Build        string       `json:",omitempty"`
+	Commands     []string     `json:",omitempty"`
+	Capabilities []Capability `json:",omitempty"`
+	Framing      framing
+	MaxSize      int
+}
+
+func newHello() *Hello {
+	return &Hello{
+		Version:      ProtocolVersion,
+		MinVersion:   minProtocolVersion,
+		Build:        BuildVersion,
+		Capabilities: capabilities,
+	}
+}
+
+// newLegacyHello 旧版服务端的等效握手内容
+func newLegacyHello() *Hello {
+	return &Hello{
+		Version:  1,
+		Commands: legacyCommands,
+		Framing:  framingLine,
+	}
+}
+
+// Supports 对端是否支持指定命令，未声明命令列表时视为支持
+func (h *Hello) Supports(cmd string) bool {
+	if h == nil || len(h.Commands) == 0 {
+		return true
+	}
+
+	return slices.Contains(h.Commands, cmd)
+}
+
+func (h *Hello) HasCapability(c Capability) bool {
+	if h == nil {
+		return false
+	}
+
+	return slices.Contains(h.Capabilities, c)
+}
+
+// compatible 校验本端与对端的协议版本是否兼容
+func (h *Hello) compatible() error {
+	switch {
+	case h.Version < minProtocolVersion:
+		return fmt.Errorf(
+			"%w: peer protocol v%d is older than minimum v%d, please upgrade peer",
+			ErrIncompatibleProtocol, h.Version, minProtocolVersion,
+		)
+	case h.MinVersion > ProtocolVersion:
+		return fmt.Errorf(
+			"%w: peer requires protocol v%d, local is v%d, please upgrade",
+			ErrIncompatibleProtocol, h.MinVersion, ProtocolVersion,
+		)
+	default:
+		return nil
+	}
+}
+
+// supportedCommands 服务端支持的命令列表
+func supportedCommands() []string {
+	names := []string{}
+	for _, spec := range CommandSpecs() {
+		names = append(names, spec.Name)
+	}
+
+	return names
+}
+
+func newHelloMessage(hello *Hello) (*Message, error) {
+	data, err := json.Marshal(hello)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgHello,
+		data:    data,
+	}, nil
+}
+
+func (m *Message) getHello() (*Hello, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgHello {
+		return nil, fmt.Errorf("%w: not a hello msg", ErrInvalidMsgType)
+	}
+
+	return getData[Hello](m.data)
+}
@@ -0,0 +1,105 @@
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+)
+
+// fakeServer 仅处理首个连接的首条消息，以 reply 生成应答
+func fakeServer(t *testing.T, reply func() *Message) string {
+	t.Helper()
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listen.Close() })
+
+	go func() {
+		var conns []net.Conn
+		defer func() {
+			for _, conn := range conns {
+				conn.Close()
+			}
+		}()
+
+		for {
+			conn, err := listen.Accept()
+			if err != nil {
+				return
+			}
+			conns = append(conns, conn)
+
+			if _, err = bufio.NewReader(conn).ReadBytes('\n'); err != nil {
+				continue
+			}
+
+			if msg := reply(); msg != nil {
+				line, _ := json.Marshal(msg)
+				conn.Write(append(line, '\n'))
+			}
+
+			reply = func() *Message { return nil }
+		}
+	}()
+
+	return listen.Addr().String()
+}
+
+func TestHello(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	client, err := NewCtlTcpClient(hdl.listen.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.conn.Close()
+
+	if hello := client.GetServerHello(); hello == nil ||
+		hello.Version != ProtocolVersion ||
+		!hello.Supports("subscribe") ||
+		!hello.HasCapability(CapTopic) {
+		t.Fatalf("invalid server hello: %+v", hello)
+	}
+
+	// 旧版服务端将握手消息视为命令并返回错误结果
+	legacy, err := NewCtlTcpClient(fakeServer(t, func() *Message {
+		data, _ := json.Marshal(&Result{Rtn: 1, Message: "unknown msg"})
+		return &Message{msgType: MsgResult, data: data}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer legacy.conn.Close()
+
+	if hello := legacy.GetServerHello(); hello == nil || hello.Version != 1 {
+		t.Fatalf("legacy server hello mismatch: %+v", hello)
+	}
+
+	if err = legacy.Command(&Command{Name: "subscribe"}); !errors.Is(
+		err, ErrUnsupportedCommand,
+	) {
+		t.Fatalf("unsupported command not rejected: %+v", err)
+	}
+
+	// 要求更高协议版本的服务端
+	if _, err = NewCtlTcpClient(fakeServer(t, func() *Message {
+		hello := newHello()
+		hello.Version = ProtocolVersion + 1
+		hello.MinVersion = ProtocolVersion + 1
+		hello.MaxSize = defaultMaxMsgSize
+
+		msg, _ := newHelloMessage(hello)
+		return msg
+	})); !errors.Is(err, ErrIncompatibleProtocol) {
+		t.Fatalf("incompatible protocol not rejected: %+v", err)
+	}
+}
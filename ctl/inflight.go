@@ -0,0 +1,292 @@
+package ctl
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCommandTimeout   = errors.New("command execution timeout")
+	ErrCommandCancelled = errors.New("command cancelled")
+	ErrInflightNotFound = errors.New("in-flight command not found")
+)
+
+const (
+	// defaultCmdTimeout 命令执行的默认超时，可由命令参数 timeout 覆盖
+	defaultCmdTimeout = time.Second * 30
+	// maxCmdTimeout 命令参数 timeout 允许的最大值
+	maxCmdTimeout = time.Minute * 10
+	// progressInterval 命令执行期间定期发送进度通知的间隔
+	progressInterval = time.Second * 2
+)
+
+// Progress 命令执行完成前的进度通知，与命令使用相同的 msgID
+type Progress struct {
+	MsgID   uint64 `json:"-"`
+	CmdName string
+	Stage   string
+	Message string `json:",omitempty"`
+	Elapsed time.Duration
+}
+
+// commandTimeout 解析并移除命令参数中的 timeout，避免传递至命令自身的参数解析，
+// 未指定时返回 0
+func commandTimeout(cmd *Command) (time.Duration, error) {
+	v, exist := cmd.KwArgs["timeout"]
+	if !exist {
+		return 0, nil
+	}
+
+	delete(cmd.KwArgs, "timeout")
+
+	timeout, err := time.ParseDuration(v)
+	if err != nil || timeout <= 0 || timeout > maxCmdTimeout {
+		return 0, fmt.Errorf(
+			"%w: invalid command timeout %s", ErrInvalidMsgData, v,
+		)
+	}
+
+	return timeout, nil
+}
+
+// inflightKey 以来源会话及客户端消息ID 标识执行中的命令
+type inflightKey struct {
+	session *session
+	id      uint64
+}
+
+// inflightCommand 执行中的命令，结果写入后不再发送进度通知
+type inflightCommand struct {
+	hdl     Handler
+	msgID   uint64
+	name    string
+	started time.Time
+	cancel  context.CancelCauseFunc
+
+	lock     sync.Mutex
+	running  bool
+	finished bool
+}
+
+func (c *inflightCommand) publish(msgType messageType, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return c.hdl.Publish(&Message{
+		msgID:   c.msgID,
+		msgType: msgType,
+		data:    data,
+	}, time.Second*3)
+}
+
+func (c *inflightCommand) progress(stage, message string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.finished {
+		return
+	}
+
+	if err := c.publish(MsgProgress, &Progress{
+		CmdName: c.name,
+		Stage:   stage,
+		Message: message,
+		Elapsed: time.Since(c.started),
+	}); err != nil {
+		slog.Error(
+			"write command progress failed",
+			slog.Any("error", err),
+			slog.String("cmd", c.name),
+			slog.Uint64("msg_id", c.msgID),
+		)
+	}
+}
+
+// start 标记命令开始执行，已结束的命令返回 false
+func (c *inflightCommand) start() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.running = !c.finished
+
+	return c.running
+}
+
+// abort 结束尚未开始执行的命令，执行中的命令返回 false，其结果由命令完成时写入
+func (c *inflightCommand) abort(result *Result) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.running {
+		return false
+	}
+
+	c.finishLocked(result)
+
+	return true
+}
+
+func (c *inflightCommand) finish(result *Result) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.finishLocked(result)
+}
+
+func (c *inflightCommand) finishLocked(result *Result) {
+	c.finished = true
+
+	if result == nil {
+		return
+	}
+
+	if err := c.publish(MsgResult, result); err != nil {
+		slog.Error(
+			"write message to handler failed",
+			slog.Any("error", err),
+			slog.String("cmd", c.name),
+			slog.Uint64("msg_id", c.msgID),
+		)
+	}
+}
+
+// abortRtn 命令因超时或取消结束时的返回码
+func abortRtn(cause error) int {
+	if errors.Is(cause, ErrCommandTimeout) {
+		return RtnTimeout
+	}
+
+	return RtnCancelled
+}
+
+// dispatch 在独立协程中执行命令，命令完成前定期发送执行进度。
+// 超时或取消在等待串行锁期间立即结束命令，开始执行后仅通过 ctx 通知命令自身，
+// 结果总在命令返回后写入；串行命令开始执行后仅受显式指定的 timeout 限制
+func (svr *CtlServer) dispatch(hdl Handler, msg *Message, cmd *Command) {
+	inflight := &inflightCommand{
+		hdl:     hdl,
+		msgID:   msg.msgID,
+		name:    cmd.Name,
+		started: time.Now(),
+	}
+
+	timeout, err := commandTimeout(cmd)
+	if err != nil {
+		inflight.finish(&Result{
+			Rtn:     1,
+			Message: err.Error(),
+			CmdName: cmd.Name,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithCancelCause(svr.ctx)
+	defer cancel(nil)
+	waitCtx, stop := context.WithTimeoutCause(
+		ctx, cmp.Or(timeout, defaultCmdTimeout), ErrCommandTimeout,
+	)
+	defer stop()
+
+	inflight.cancel = cancel
+
+	key := inflightKey{session: msg.session, id: msg.clientID}
+	if key.id == 0 {
+		key.id = msg.msgID
+	}
+	svr.inflight.Store(key, inflight)
+	defer svr.inflight.CompareAndDelete(key, inflight)
+
+	cmd.progress = inflight.progress
+
+	done := make(chan *Result, 1)
+
+	go func() {
+		execCtx := waitCtx
+
+		if spec, exist := GetCommandSpec(cmd.Name); !exist || !spec.Concurrent {
+			svr.cmdLock.Lock()
+			defer svr.cmdLock.Unlock()
+
+			// 串行命令可能包含插件初始化等无法中断的操作，不使用默认超时
+			if timeout == 0 {
+				execCtx = ctx
+			}
+		}
+
+		// 等待期间已超时或被取消的命令不再执行
+		if waitCtx.Err() != nil || !inflight.start() {
+			return
+		}
+
+		result, err := svr.execute(execCtx, msg, cmd)
+		if err != nil {
+			slog.Error(
+				"execute command failed",
+				slog.Any("error", err),
+				slog.Any("msg", msg),
+			)
+		}
+
+		// 响应 ctx 的命令因超时或取消失败时以对应返回码区分
+		if cause := context.Cause(execCtx); cause != nil &&
+			result != nil && result.Rtn != 0 {
+			result.Rtn = abortRtn(cause)
+		}
+
+		done <- result
+	}()
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	aborted := waitCtx.Done()
+
+	for {
+		select {
+		case result := <-done:
+			inflight.finish(result)
+			return
+		case <-ticker.C:
+			inflight.progress("running", "")
+		case <-aborted:
+			cause := context.Cause(waitCtx)
+
+			if inflight.abort(&Result{
+				Rtn:     abortRtn(cause),
+				Message: cause.Error(),
+				CmdName: cmd.Name,
+			}) {
+				slog.Warn(
+					"command execution aborted",
+					slog.Any("cause", cause),
+					slog.String("cmd", cmd.Name),
+					slog.Uint64("msg_id", msg.msgID),
+				)
+				return
+			}
+
+			// 已开始执行的命令等待其返回，取消仍经 ctx 通知命令
+			aborted = nil
+		}
+	}
+}
+
+// cancelInflight 取消会话中指定客户端消息ID 的执行中命令
+func (svr *CtlServer) cancelInflight(sess *session, id uint64) error {
+	value, exist := svr.inflight.Load(inflightKey{session: sess, id: id})
+	if !exist {
+		return fmt.Errorf("%w: %d", ErrInflightNotFound, id)
+	}
+
+	value.(*inflightCommand).cancel(ErrCommandCancelled)
+
+	return nil
+}
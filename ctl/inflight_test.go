@@ -0,0 +1,147 @@
+package ctl
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+func TestCommandCancel(t *testing.T) {
+	svr, err := NewCtlServer(
+		t.Context(), (&CtlSvrHdlConfig{}).Tcp("127.0.0.1:0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	go svr.runForever()
+	defer svr.cancel()
+
+	client, err := NewCtlTcpClient(
+		svr.handlers[0].(*CtlTcpHandler).listen.Addr().String() + "?reconnect=false",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	progress := make(chan *Progress, 10)
+	client.ProgressLoop("test progress", func(p *Progress) error {
+		progress <- p
+		return nil
+	})
+
+	// 持有串行锁，模拟长耗时的变更命令
+	svr.cmdLock.Lock()
+	defer svr.cmdLock.Unlock()
+
+	result, err := client.Call(t.Context(), &Command{
+		Name: "stop", KwArgs: map[string]string{"timeout": "100ms"},
+	})
+	if err != nil || result.Rtn != RtnTimeout {
+		t.Fatalf("command not timeout: %+v, %+v", result, err)
+	}
+
+	// 只读命令不受阻塞的变更命令影响
+	if result, err = client.Call(
+		t.Context(), &Command{Name: "sessions"},
+	); err != nil || result.Rtn != 0 {
+		t.Fatalf("concurrent command failed: %+v, %+v", result, err)
+	}
+
+	stopped := make(chan *Result, 1)
+	go func() {
+		result, _ := client.Call(t.Context(), &Command{Name: "stop"})
+		stopped <- result
+	}()
+
+	var running *Progress
+	select {
+	case running = <-progress:
+		if running.CmdName != "stop" || running.Stage != "running" {
+			t.Fatalf("progress mismatch: %+v", running)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait command progress timeout")
+	}
+
+	if result, err = client.Call(t.Context(), &Command{
+		Name:   "cancel",
+		KwArgs: map[string]string{"id": strconv.FormatUint(running.MsgID, 10)},
+	}); err != nil || result.Rtn != 0 {
+		t.Fatalf("cancel command failed: %+v, %+v", result, err)
+	}
+
+	select {
+	case result = <-stopped:
+		if result == nil || result.Rtn != RtnCancelled {
+			t.Fatalf("command not cancelled: %+v", result)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait cancelled result timeout")
+	}
+}
+
+func TestCommandRunningTimeout(t *testing.T) {
+	for _, spec := range []CommandSpec{
+		{
+			Name: "slow",
+			Handler: func(_ context.Context, _ *CtlServer, _ *Command, result *Result) error {
+				time.Sleep(time.Millisecond * 300)
+				result.Message = "done"
+				return nil
+			},
+		},
+		{
+			Name: "wait",
+			Handler: func(ctx context.Context, _ *CtlServer, _ *Command, _ *Result) error {
+				<-ctx.Done()
+				return context.Cause(ctx)
+			},
+		},
+	} {
+		spec.ClientFree = true
+		if err := RegisterCommand(spec); err != nil {
+			t.Fatal(err)
+		}
+		defer UnRegisterCommand(spec.Name)
+	}
+
+	svr, err := NewCtlServer(
+		t.Context(), (&CtlSvrHdlConfig{}).Tcp("127.0.0.1:0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	go svr.runForever()
+	defer svr.cancel()
+
+	client, err := NewCtlTcpClient(
+		svr.handlers[0].(*CtlTcpHandler).listen.Addr().String() + "?reconnect=false",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	// 不响应 ctx 的执行中命令返回实际结果，而非提前返回超时
+	result, err := client.Call(t.Context(), &Command{
+		Name: "slow", KwArgs: map[string]string{"timeout": "50ms"},
+	})
+	if err != nil || result.Rtn != 0 || result.Message != "done" {
+		t.Fatalf("running command result mismatch: %+v, %+v", result, err)
+	}
+
+	if result, err = client.Call(t.Context(), &Command{
+		Name: "wait", KwArgs: map[string]string{"timeout": "50ms"},
+	}); err != nil || result.Rtn != RtnTimeout {
+		t.Fatalf("command not timeout: %+v, %+v", result, err)
+	}
+}
@@ -0,0 +1,196 @@
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// logQueueSize 待广播日志的缓冲数量，缓冲满时丢弃新日志，避免阻塞业务日志输出
+const logQueueSize = 1024
+
+// LogRecord 服务端日志记录
+type LogRecord struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	Attrs   map[string]string `json:",omitempty"`
+}
+
+func newLogMessage(record *LogRecord) (*Message, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgLog,
+		topic:   TopicLog,
+		data:    data,
+		level:   record.Level,
+	}, nil
+}
+
+type logSink struct {
+	svr     atomic.Pointer[CtlServer]
+	records chan *LogRecord
+	dropped atomic.Uint64
+}
+
+// LogHandler slog.Handler 分流器，日志写入 next 的同时广播至订阅 log 主题的控制台连接
+type LogHandler struct {
+	next   slog.Handler
+	attrs  []slog.Attr
+	groups []string
+	sink   *logSink
+}
+
+func NewLogHandler(next slog.Handler) *LogHandler {
+	return &LogHandler{
+		next: next,
+		sink: &logSink{
+			records: make(chan *LogRecord, logQueueSize),
+		},
+	}
+}
+
+func (hdl *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return hdl.next.Enabled(ctx, level)
+}
+
+func (hdl *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := hdl.next.Handle(ctx, r)
+
+	if hdl.sink.svr.Load() == nil {
+		return err
+	}
+
+	record := LogRecord{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]string, len(hdl.attrs)+r.NumAttrs()),
+	}
+
+	for _, attr := range hdl.attrs {
+		addLogAttr(record.Attrs, "", attr)
+	}
+
+	prefix := groupPrefix(hdl.groups)
+	r.Attrs(func(attr slog.Attr) bool {
+		addLogAttr(record.Attrs, prefix, attr)
+		return true
+	})
+
+	select {
+	case hdl.sink.records <- &record:
+	default:
+		hdl.sink.dropped.Add(1)
+	}
+
+	return err
+}
+
+func (hdl *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handler := *hdl
+	handler.next = hdl.next.WithAttrs(attrs)
+
+	prefix := groupPrefix(hdl.groups)
+	handler.attrs = slices.Clone(hdl.attrs)
+	for _, attr := range attrs {
+		attr.Key = prefix + attr.Key
+		handler.attrs = append(handler.attrs, attr)
+	}
+
+	return &handler
+}
+
+func (hdl *LogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return hdl
+	}
+
+	handler := *hdl
+	handler.next = hdl.next.WithGroup(name)
+	handler.groups = append(slices.Clone(hdl.groups), name)
+
+	return &handler
+}
+
+// attach 开始向 svr 的广播通道投递日志
+func (hdl *LogHandler) attach(svr *CtlServer) {
+	if !hdl.sink.svr.CompareAndSwap(nil, svr) {
+		return
+	}
+
+	go func() {
+		for {
+			select {
+			case <-svr.ctx.Done():
+				hdl.sink.svr.CompareAndSwap(svr, nil)
+				return
+			case record := <-hdl.sink.records:
+				if hdl.sink.svr.Load() != svr {
+					return
+				}
+
+				msg, err := newLogMessage(record)
+				if err != nil {
+					continue
+				}
+
+				// 广播失败时不再记录日志，避免日志循环
+				if svr.broadcast.Publish(msg, time.Second) != nil {
+					hdl.sink.dropped.Add(1)
+				}
+			}
+		}
+	}()
+}
+
+func (hdl *LogHandler) detach(svr *CtlServer) {
+	hdl.sink.svr.CompareAndSwap(svr, nil)
+}
+
+// Dropped 因缓冲满或广播失败而丢弃的日志数量
+func (hdl *LogHandler) Dropped() uint64 {
+	return hdl.sink.dropped.Load()
+}
+
+func groupPrefix(groups []string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	prefix := ""
+	for _, group := range groups {
+		prefix += group + "."
+	}
+
+	return prefix
+}
+
+func addLogAttr(attrs map[string]string, prefix string, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "."
+		}
+
+		for _, sub := range attr.Value.Group() {
+			addLogAttr(attrs, prefix, sub)
+		}
+
+		return
+	}
+
+	if attr.Key == "" {
+		return
+	}
+
+	attrs[prefix+attr.Key] = attr.Value.String()
+}
@@ -0,0 +1,47 @@
+package ctl
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/frozenpine/msgqueue/core"
+)
+
+func TestLogHandler(t *testing.T) {
+	tee := NewLogHandler(slog.NewTextHandler(io.Discard, nil))
+
+	svr, err := NewCtlServer(t.Context(), (&CtlSvrHdlConfig{}).Logs(tee))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svr.Stop()
+
+	_, notify := svr.broadcast.Subscribe("test", core.Quick)
+
+	logger := slog.New(tee).With("handler", "test").WithGroup("cmd")
+	logger.Debug("filtered by next handler")
+	logger.Warn("command denied", slog.String("name", "stop"))
+
+	select {
+	case msg := <-notify:
+		record, err := msg.GetLog()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if record.Level != slog.LevelWarn || record.Message != "command denied" ||
+			record.Attrs["handler"] != "test" || record.Attrs["cmd.name"] != "stop" {
+			t.Fatalf("invalid log record: %+v", record)
+		}
+
+		subs := newSubscriptions()
+		subs.set(TopicLog, SubOption{Level: slog.LevelError})
+		if result := subs.filter(msg); len(result) != 0 {
+			t.Fatalf("log level not filtered: %+v", result)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait log message timeout")
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"strconv"
+	"time"
 
 	"github.com/frozenpine/latency4go"
 	"github.com/valyala/bytebufferpool"
@@ -18,6 +20,14 @@ const (
 	MsgCommand                      // Command
 	MsgResult                       // Result
 	MsgBroadCast                    // BroadCast
+	MsgAuth                         // Auth
+	MsgPing                         // Ping
+	MsgPong                         // Pong
+	MsgEvent                        // Event
+	MsgLog                          // Log
+	MsgHello                        // Hello
+	MsgProgress                     // Progress
+	MsgClose                        // Close
 )
 
 var (
@@ -25,7 +35,7 @@ var (
 	ErrInvalidMsgData = errors.New("invalid msg data")
 )
 
-func getData[T Command | Result | latency4go.State](data []byte) (*T, error) {
+func getData[T Command | Result | latency4go.State | authData | heartbeatData | Event | LogRecord | Hello | Progress | closeData](data []byte) (*T, error) {
 	if len(data) <= 0 {
 		return nil, nil
 	}
@@ -45,16 +55,28 @@ type Message struct {
 	msgID   uint64
 	msgType messageType
 	data    []byte
+	// 广播消息主题，State 广播以外的主题需客户端订阅
+	topic Topic
+	// 命令来源会话，仅服务端内部使用，不参与序列化
+	session *session
+	// 客户端发送命令时使用的原始消息ID，Handler 重映射消息ID 时设置，仅服务端内部使用
+	clientID uint64
+	// 广播 State 的解析结果，仅服务端内部使用，不参与序列化
+	state *latency4go.State
+	// 日志广播的级别，仅服务端内部使用，不参与序列化
+	level slog.Level
 }
 
 func (m *Message) MarshalJSON() ([]byte, error) {
 	return json.Marshal(&struct {
 		MsgID   uint64
 		MsgType messageType
+		Topic   Topic `json:",omitempty"`
 		Data    []byte
 	}{
 		MsgID:   m.msgID,
 		MsgType: m.msgType,
+		Topic:   m.topic,
 		Data:    m.data,
 	})
 }
@@ -63,6 +85,7 @@ func (m *Message) UnmarshalJSON(v []byte) error {
 	var d struct {
 		MsgID   uint64
 		MsgType messageType
+		Topic   Topic
 		Data    []byte
 	}
 
@@ -72,6 +95,7 @@ func (m *Message) UnmarshalJSON(v []byte) error {
 
 	m.msgID = d.MsgID
 	m.msgType = d.MsgType
+	m.topic = d.Topic
 	m.data = d.Data
 
 	return nil
@@ -89,6 +113,10 @@ func (m *Message) GetID() uint64 {
 	return m.msgID
 }
 
+func (m *Message) GetTopic() Topic {
+	return m.topic
+}
+
 func (m *Message) GetCommand() (*Command, error) {
 	if m == nil {
 		return nil, ErrInvalidMsgType
@@ -125,6 +153,147 @@ func (m *Message) GetState() (*latency4go.State, error) {
 	return getData[latency4go.State](m.data)
 }
 
+func (m *Message) GetLog() (*LogRecord, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgLog {
+		return nil, fmt.Errorf("%w: not a log msg", ErrInvalidMsgType)
+	}
+
+	return getData[LogRecord](m.data)
+}
+
+func (m *Message) GetProgress() (*Progress, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgProgress {
+		return nil, fmt.Errorf("%w: not a progress msg", ErrInvalidMsgType)
+	}
+
+	progress, err := getData[Progress](m.data)
+	if progress != nil {
+		progress.MsgID = m.msgID
+	}
+
+	return progress, err
+}
+
+func (m *Message) GetEvent() (*Event, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgEvent {
+		return nil, fmt.Errorf("%w: not an event msg", ErrInvalidMsgType)
+	}
+
+	return getData[Event](m.data)
+}
+
+func (m *Message) getAuth() (*authData, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgAuth {
+		return nil, fmt.Errorf("%w: not an auth msg", ErrInvalidMsgType)
+	}
+
+	return getData[authData](m.data)
+}
+
+func newAuthMessage(auth *authData) (*Message, error) {
+	data, err := json.Marshal(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgAuth,
+		data:    data,
+	}, nil
+}
+
+// closeData 服务端主动断开连接前通知的断开原因，客户端收到后不再自动重连
+type closeData struct {
+	Reason string
+}
+
+func newCloseMessage(reason string) (*Message, error) {
+	data, err := json.Marshal(&closeData{Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgClose,
+		data:    data,
+	}, nil
+}
+
+func (m *Message) getClose() (*closeData, error) {
+	if m == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgClose {
+		return nil, fmt.Errorf("%w: not a close msg", ErrInvalidMsgType)
+	}
+
+	return getData[closeData](m.data)
+}
+
+// heartbeatData 心跳消息内容，Pong 原样返回 Ping 的发送时间用于计算往返时延
+type heartbeatData struct {
+	Timestamp int64
+}
+
+func newPingMessage() (*Message, error) {
+	data, err := json.Marshal(&heartbeatData{
+		Timestamp: time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgPing,
+		data:    data,
+	}, nil
+}
+
+// newPongMessage 以 Ping 消息内容构造应答
+func newPongMessage(ping *Message) *Message {
+	return &Message{
+		msgType: MsgPong,
+		data:    ping.data,
+	}
+}
+
+// getRTT 根据 Pong 消息中回传的 Ping 发送时间计算往返时延
+func (m *Message) getRTT() (time.Duration, error) {
+	if m == nil {
+		return 0, ErrInvalidMsgType
+	}
+
+	if m.msgType != MsgPong {
+		return 0, fmt.Errorf("%w: not a pong msg", ErrInvalidMsgType)
+	}
+
+	hb, err := getData[heartbeatData](m.data)
+	if err != nil {
+		return 0, err
+	} else if hb == nil {
+		return 0, fmt.Errorf("%w: empty pong msg", ErrInvalidMsgData)
+	}
+
+	return time.Since(time.Unix(0, hb.Timestamp)), nil
+}
+
 func (m *Message) String() string {
 	buff := bytebufferpool.Get()
 	defer bytebufferpool.Put(buff)
@@ -12,11 +12,19 @@ func _() {
 	_ = x[MsgCommand-1]
 	_ = x[MsgResult-2]
 	_ = x[MsgBroadCast-3]
+	_ = x[MsgAuth-4]
+	_ = x[MsgPing-5]
+	_ = x[MsgPong-6]
+	_ = x[MsgEvent-7]
+	_ = x[MsgLog-8]
+	_ = x[MsgHello-9]
+	_ = x[MsgProgress-10]
+	_ = x[MsgClose-11]
 }
 
-const _messageType_name = "UnknownCommandResultBroadCast"
+const _messageType_name = "UnknownCommandResultBroadCastAuthPingPongEventLogHelloProgressClose"
 
-var _messageType_index = [...]uint8{0, 7, 14, 20, 29}
+var _messageType_index = [...]uint8{0, 7, 14, 20, 29, 33, 37, 41, 46, 49, 54, 62, 67}
 
 func (i messageType) String() string {
 	if i >= messageType(len(_messageType_index)-1) {
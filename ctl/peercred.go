@@ -0,0 +1,30 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"os/user"
+	"strconv"
+)
+
+var (
+	ErrPeerCredUnsupported = errors.New("peer credential not supported")
+)
+
+// peerCred unix domain socket 对端进程凭证
+type peerCred struct {
+	uid uint32
+	gid uint32
+	pid int32
+}
+
+// identity 以对端用户名作为连接身份，用户不存在时为 uid:{uid}
+func (cred *peerCred) identity() string {
+	uid := strconv.FormatUint(uint64(cred.uid), 10)
+
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+
+	return fmt.Sprint("uid:", uid)
+}
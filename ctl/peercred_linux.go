@@ -0,0 +1,42 @@
+package ctl
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredential 通过 SO_PEERCRED 获取 unix domain socket 对端进程凭证
+func peerCredential(conn net.Conn) (*peerCred, error) {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return nil, ErrPeerCredUnsupported
+	}
+
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		ucred   *syscall.Ucred
+		credErr error
+	)
+
+	if err = raw.Control(func(fd uintptr) {
+		ucred, credErr = syscall.GetsockoptUcred(
+			int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED,
+		)
+	}); err != nil {
+		return nil, err
+	}
+
+	if credErr != nil {
+		return nil, credErr
+	}
+
+	return &peerCred{
+		uid: ucred.Uid,
+		gid: ucred.Gid,
+		pid: ucred.Pid,
+	}, nil
+}
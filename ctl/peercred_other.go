@@ -0,0 +1,9 @@
+//go:build !linux
+
+package ctl
+
+import "net"
+
+func peerCredential(net.Conn) (*peerCred, error) {
+	return nil, ErrPeerCredUnsupported
+}
@@ -0,0 +1,196 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+var (
+	ErrInvalidPush = errors.New("invalid push args")
+)
+
+func splitList(v string) []string {
+	items := []string{}
+
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// parseUntil 解析锁定截止时间，支持 RFC3339 时间或相对当前时间的时长
+func parseUntil(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	var until time.Time
+	if dur, err := time.ParseDuration(v); err == nil {
+		until = time.Now().Add(dur)
+	} else if until, err = time.Parse(time.RFC3339, v); err != nil {
+		return until, fmt.Errorf("%w: invalid until %s", ErrInvalidPush, v)
+	}
+
+	if !until.After(time.Now()) {
+		return until, fmt.Errorf("%w: until %s already passed", ErrInvalidPush, v)
+	}
+
+	return until, nil
+}
+
+// pushState 按显式地址列表或当前状态的排名构造推送状态，
+// 地址在当前状态中的延迟数据保留，不存在的地址仅包含前置地址
+func pushState(
+	client *latency4go.LatencyClient, addrs, order string,
+) (*latency4go.State, error) {
+	if (addrs == "") == (order == "") {
+		return nil, fmt.Errorf(
+			"%w: either addrs or order required", ErrInvalidPush,
+		)
+	}
+
+	last := client.GetLastState()
+	latency := []*latency4go.ExFrontLatency{}
+
+	if addrs != "" {
+		for _, addr := range splitList(addrs) {
+			if slices.ContainsFunc(latency, func(v *latency4go.ExFrontLatency) bool {
+				return v.FrontAddr == addr
+			}) {
+				return nil, fmt.Errorf(
+					"%w: duplicated addr %s", ErrInvalidPush, addr,
+				)
+			}
+
+			front := &latency4go.ExFrontLatency{FrontAddr: addr}
+			if last != nil {
+				if idx := slices.IndexFunc(last.LatencyList, func(v *latency4go.ExFrontLatency) bool {
+					return v.FrontAddr == addr
+				}); idx >= 0 {
+					front = last.LatencyList[idx]
+				}
+			}
+
+			latency = append(latency, front)
+		}
+	} else {
+		if last == nil || len(last.LatencyList) == 0 {
+			return nil, fmt.Errorf(
+				"%w: no current state to reorder", ErrInvalidPush,
+			)
+		}
+
+		used := make([]bool, len(last.LatencyList))
+
+		for _, v := range splitList(order) {
+			rank, err := strconv.Atoi(v)
+			if err != nil || rank < 1 || rank > len(last.LatencyList) || used[rank-1] {
+				return nil, fmt.Errorf(
+					"%w: invalid rank %s", ErrInvalidPush, v,
+				)
+			}
+
+			used[rank-1] = true
+			latency = append(latency, last.LatencyList[rank-1])
+		}
+
+		for idx, front := range last.LatencyList {
+			if !used[idx] {
+				latency = append(latency, front)
+			}
+		}
+	}
+
+	if len(latency) == 0 {
+		return nil, fmt.Errorf("%w: empty addr list", ErrInvalidPush)
+	}
+
+	return latency4go.NewState(time.Now(), client.GetConfig(), latency), nil
+}
+
+func cmdPush(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+
+	until, err := parseUntil(cmd.KwArgs["until"])
+	if err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return err
+	}
+
+	state, err := pushState(client, cmd.KwArgs["addrs"], cmd.KwArgs["order"])
+	if err != nil {
+		result.Rtn = 1
+		result.Message = err.Error()
+		return err
+	}
+
+	plugins := pluginTargets(cmd.KwArgs["plugin"])
+	if len(plugins) == 0 {
+		result.Rtn = 1
+		result.Message = "no plugin loaded"
+		return nil
+	}
+
+	err = client.Push(state, until, plugins...)
+
+	result.Values[VKeyState] = state
+	result.Values[VKeyPin] = client.GetPins()
+
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf("push priority failed: %+v", err)
+	} else {
+		result.Message = "priority pushed"
+	}
+
+	message := fmt.Sprintf(
+		"priority %v pushed to %s", state.AddrList, strings.Join(plugins, ","),
+	)
+	if !until.IsZero() {
+		message += ", pinned until " + until.Format(time.RFC3339)
+	}
+	if cmd.session != nil {
+		message += " by " + cmd.session.remote
+	}
+	if err != nil {
+		message += ": " + err.Error()
+	}
+
+	svr.alert(slog.LevelWarn, "push", message)
+
+	return err
+}
+
+func cmdUnpin(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+	plugins := splitList(cmd.KwArgs["plugin"])
+
+	client.Unpin(plugins...)
+
+	result.Values[VKeyPin] = client.GetPins()
+	result.Message = "plugins unpinned"
+
+	target := "all plugins"
+	if len(plugins) > 0 {
+		target = strings.Join(plugins, ",")
+	}
+
+	svr.alert(
+		slog.LevelInfo, "push",
+		target+" unpinned, periodic report resumed",
+	)
+
+	return nil
+}
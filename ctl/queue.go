@@ -0,0 +1,385 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrSendQueueFull         = errors.New("send queue full")
+	ErrSendQueueClosed       = errors.New("send queue closed")
+	ErrInvalidOverflowPolicy = errors.New("invalid overflow policy")
+)
+
+// defaultSendQueueSize 每个连接待发送消息的默认缓冲数量
+const defaultSendQueueSize = 256
+
+// kickWriteTimeout 断开连接前写入断开原因的最长等待时间
+const kickWriteTimeout = time.Second
+
+// OverflowPolicy 连接发送队列满时的处理策略
+type OverflowPolicy uint8
+
+const (
+	// OverflowDropOldest 丢弃队列中最早的可丢弃消息（State 广播、服务端日志）
+	OverflowDropOldest OverflowPolicy = iota
+	// OverflowCoalesce 以新消息替换队列中同类型、同主题的消息，仅保留最新值
+	OverflowCoalesce
+	// OverflowDisconnect 断开处理过慢的连接
+	OverflowDisconnect
+)
+
+var overflowPolicyNames = [...]string{"drop", "coalesce", "disconnect"}
+
+func (p OverflowPolicy) String() string {
+	if int(p) < len(overflowPolicyNames) {
+		return overflowPolicyNames[p]
+	}
+
+	return fmt.Sprintf("OverflowPolicy(%d)", p)
+}
+
+func (p OverflowPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *OverflowPolicy) UnmarshalText(v []byte) error {
+	for idx, name := range overflowPolicyNames {
+		if string(v) == name {
+			*p = OverflowPolicy(idx)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrInvalidOverflowPolicy, v)
+}
+
+// QueueStat 连接发送队列的统计信息
+type QueueStat struct {
+	Handler  string
+	Remote   string
+	Identity string `json:",omitempty"`
+	Policy   OverflowPolicy
+	Size     int
+	Depth    int
+	Sent     uint64
+	Dropped  uint64
+}
+
+// queueOptions 连接发送队列参数，由 Handler 的连接参数解析
+type queueOptions struct {
+	size   int
+	policy OverflowPolicy
+}
+
+// parseQueueOptions 解析 sendq 及 overflow 连接参数
+func parseQueueOptions(opts url.Values) (queueOptions, error) {
+	qOpts := queueOptions{
+		size:   defaultSendQueueSize,
+		policy: OverflowDropOldest,
+	}
+
+	if v := opts.Get("sendq"); v != "" {
+		size, err := strconv.Atoi(v)
+		if err != nil || size <= 0 {
+			return qOpts, fmt.Errorf("invalid send queue size: %s", v)
+		}
+
+		qOpts.size = size
+	}
+
+	if v := opts.Get("overflow"); v != "" {
+		if err := qOpts.policy.UnmarshalText([]byte(v)); err != nil {
+			return qOpts, err
+		}
+	}
+
+	return qOpts, nil
+}
+
+// droppable 队列满时可丢弃的消息，命令结果及事件不丢弃
+func droppable(msg *Message) bool {
+	return msg.msgType == MsgBroadCast || msg.msgType == MsgLog
+}
+
+// oversizeResult 生成替换超长命令结果的错误结果，保留原消息ID 及命令名称
+func oversizeResult(msg *Message, err error) *Message {
+	result := Result{
+		Rtn:     RtnTooLarge,
+		Message: fmt.Sprintf("result exceeds max frame size: %v", err),
+	}
+
+	if origin, _ := msg.GetResult(); origin != nil {
+		result.CmdName = origin.CmdName
+	}
+
+	rsp := *msg
+	rsp.data, _ = json.Marshal(&result)
+
+	return &rsp
+}
+
+// sendQueue 连接的有界发送队列，由独立协程写入连接，避免慢连接阻塞其他连接的消息分发
+type sendQueue struct {
+	name   string
+	opts   queueOptions
+	write  func(*Message) error
+	closer func() error
+
+	lock   sync.Mutex
+	msgs   []*Message
+	kicked bool
+	notify chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+}
+
+func newSendQueue(
+	name string, opts queueOptions,
+	write func(*Message) error, closer func() error,
+) *sendQueue {
+	q := sendQueue{
+		name:   name,
+		opts:   opts,
+		write:  write,
+		closer: closer,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	go q.run()
+
+	return &q
+}
+
+func (q *sendQueue) push(msg *Message) error {
+	select {
+	case <-q.done:
+		return ErrSendQueueClosed
+	default:
+	}
+
+	q.lock.Lock()
+
+	if q.kicked {
+		q.lock.Unlock()
+		q.dropped.Add(1)
+
+		return ErrSendQueueClosed
+	}
+
+	// 不可丢弃的消息允许超出容量，超出 2 倍容量时无论何种策略均断开连接
+	switch depth := len(q.msgs); {
+	case depth < q.opts.size:
+	case q.opts.policy == OverflowDisconnect, depth >= q.opts.size*2:
+		q.lock.Unlock()
+		q.dropped.Add(1)
+
+		slog.Warn(
+			"ctl connection send queue overflow, disconnecting",
+			slog.String("remote", q.name),
+			slog.Int("depth", depth),
+		)
+
+		q.shutdown()
+
+		return fmt.Errorf("%w: depth %d", ErrSendQueueFull, depth)
+	case droppable(msg) && !q.evict(msg):
+		// 队列中均为不可丢弃的消息时丢弃新消息
+		q.lock.Unlock()
+		q.dropped.Add(1)
+
+		return nil
+	}
+
+	q.msgs = append(q.msgs, msg)
+	q.lock.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// kick 丢弃队列中未发送的消息，发送断开原因后关闭连接，
+// 超过 kickWriteTimeout 仍未发送时直接关闭连接
+func (q *sendQueue) kick(reason string) error {
+	msg, err := newCloseMessage(reason)
+	if err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	select {
+	case <-q.done:
+		q.lock.Unlock()
+		return ErrSendQueueClosed
+	default:
+	}
+
+	q.dropped.Add(uint64(len(q.msgs)))
+	q.msgs = []*Message{msg}
+	q.kicked = true
+	q.lock.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+
+	time.AfterFunc(kickWriteTimeout, func() {
+		select {
+		case <-q.done:
+		default:
+			q.shutdown()
+		}
+	})
+
+	return nil
+}
+
+// evict 按溢出策略移除队列中的消息，无可移除消息时返回 false
+func (q *sendQueue) evict(msg *Message) bool {
+	before := len(q.msgs)
+
+	if q.opts.policy == OverflowCoalesce {
+		q.msgs = slices.DeleteFunc(q.msgs, func(m *Message) bool {
+			return m.msgType == msg.msgType && m.topic == msg.topic
+		})
+	}
+
+	if len(q.msgs) == before {
+		if idx := slices.IndexFunc(q.msgs, droppable); idx >= 0 {
+			q.msgs = slices.Delete(q.msgs, idx, idx+1)
+		}
+	}
+
+	q.dropped.Add(uint64(before - len(q.msgs)))
+
+	return len(q.msgs) < before
+}
+
+func (q *sendQueue) pop() *Message {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if len(q.msgs) == 0 {
+		return nil
+	}
+
+	msg := q.msgs[0]
+	q.msgs[0] = nil
+	q.msgs = q.msgs[1:]
+
+	return msg
+}
+
+func (q *sendQueue) run() {
+	for {
+		select {
+		case <-q.done:
+			return
+		case <-q.notify:
+		}
+
+		for msg := q.pop(); msg != nil; msg = q.pop() {
+			err := q.write(msg)
+			if errors.Is(err, ErrMsgTooLarge) {
+				slog.Error(
+					"discard oversize queued msg",
+					slog.Any("error", err),
+					slog.String("remote", q.name),
+					slog.String("msg_type", msg.msgType.String()),
+				)
+				q.dropped.Add(1)
+
+				// 命令结果以同一消息ID 的错误结果替换，避免客户端等待至超时
+				if msg.msgType != MsgResult {
+					continue
+				}
+
+				err = q.write(oversizeResult(msg, err))
+			}
+
+			if err != nil {
+				slog.Error(
+					"write queued msg failed",
+					slog.Any("error", err),
+					slog.String("remote", q.name),
+					slog.String("msg_type", msg.msgType.String()),
+				)
+
+				q.shutdown()
+				return
+			}
+
+			q.sent.Add(1)
+
+			if msg.msgType == MsgClose {
+				q.shutdown()
+				return
+			}
+		}
+	}
+}
+
+// shutdown 停止发送并关闭连接，连接由读协程负责移除
+func (q *sendQueue) shutdown() {
+	q.stop()
+
+	if err := q.closer(); err != nil {
+		slog.Error(
+			"close ctl connection failed",
+			slog.Any("error", err),
+			slog.String("remote", q.name),
+		)
+	}
+}
+
+// stop 停止发送，丢弃队列中剩余的消息
+func (q *sendQueue) stop() {
+	q.once.Do(func() {
+		close(q.done)
+
+		q.lock.Lock()
+		q.dropped.Add(uint64(len(q.msgs)))
+		q.msgs = nil
+		q.lock.Unlock()
+	})
+}
+
+func (q *sendQueue) stats() QueueStat {
+	q.lock.Lock()
+	depth := len(q.msgs)
+	q.lock.Unlock()
+
+	return QueueStat{
+		Policy:  q.opts.policy,
+		Size:    q.opts.size,
+		Depth:   depth,
+		Sent:    q.sent.Load(),
+		Dropped: q.dropped.Load(),
+	}
+}
+
+// queuedWriter 带发送队列的连接
+type queuedWriter interface {
+	messageWriter
+
+	queueStats() QueueStat
+	// kick 经发送队列通知断开原因后关闭连接
+	kick(reason string) error
+}
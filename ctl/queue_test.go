@@ -0,0 +1,131 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSendQueueOverflow(t *testing.T) {
+	state := func(id uint64) *Message {
+		return &Message{msgID: id, msgType: MsgBroadCast, topic: TopicState}
+	}
+	event := func(id uint64) *Message {
+		return &Message{msgID: id, msgType: MsgEvent, topic: TopicPlugin}
+	}
+
+	for _, c := range []struct {
+		policy OverflowPolicy
+		push   []*Message
+		expect []uint64
+		closed bool
+	}{
+		{OverflowDropOldest, []*Message{state(2), event(3), state(4)}, []uint64{3, 4}, false},
+		{OverflowCoalesce, []*Message{state(2), event(3), state(4), state(5)}, []uint64{3, 5}, false},
+		{OverflowDisconnect, []*Message{state(2), event(3), state(4)}, nil, true},
+	} {
+		started, gate := make(chan struct{}), make(chan struct{})
+		written := []uint64{}
+		closed := false
+
+		q := newSendQueue("test", queueOptions{size: 2, policy: c.policy},
+			func(msg *Message) error {
+				if msg.msgID == 1 {
+					close(started)
+					<-gate
+				}
+				written = append(written, msg.msgID)
+				return nil
+			},
+			func() error { closed = true; return nil },
+		)
+
+		// 首条消息阻塞在写入中，其余消息积压在队列
+		q.push(state(1))
+		<-started
+
+		var err error
+		for _, msg := range c.push {
+			if err = q.push(msg); err != nil {
+				break
+			}
+		}
+
+		if closed != c.closed {
+			t.Fatalf("%s closed mismatch: %v", c.policy, closed)
+		} else if c.closed {
+			if !errors.Is(err, ErrSendQueueFull) {
+				t.Fatalf("%s overflow error mismatch: %+v", c.policy, err)
+			}
+			close(gate)
+			continue
+		}
+
+		queued := []uint64{}
+		for _, msg := range q.msgs {
+			queued = append(queued, msg.msgID)
+		}
+		if !slices.Equal(queued, c.expect) {
+			t.Fatalf("%s queued mismatch: expect %v, got %v", c.policy, c.expect, queued)
+		}
+
+		if stat := q.stats(); stat.Depth != len(c.expect) ||
+			stat.Dropped != uint64(len(c.push)-len(c.expect)) {
+			t.Fatalf("%s stats mismatch: %+v", c.policy, stat)
+		}
+
+		close(gate)
+		q.stop()
+	}
+}
+
+func TestSendQueueOversizeResult(t *testing.T) {
+	written := make(chan *Message, 3)
+
+	q := newSendQueue("test", queueOptions{size: 4},
+		func(msg *Message) error {
+			if len(msg.data) > 128 {
+				return fmt.Errorf("%w: %d", ErrMsgTooLarge, len(msg.data))
+			}
+			written <- msg
+			return nil
+		},
+		func() error { return nil },
+	)
+	defer q.stop()
+
+	data, _ := json.Marshal(&Result{
+		CmdName: "query", Message: strings.Repeat("x", 256),
+	})
+
+	// 超长的广播直接丢弃，超长的命令结果以同一消息ID 的错误结果替换
+	q.push(&Message{msgType: MsgBroadCast, topic: TopicState, data: data})
+	q.push(&Message{msgID: 7, msgType: MsgResult, data: data})
+
+	select {
+	case msg := <-written:
+		result, err := msg.GetResult()
+		if err != nil || msg.msgID != 7 || result.Rtn != RtnTooLarge ||
+			result.CmdName != "query" {
+			t.Fatalf("oversize result not replaced: %d, %+v, %v", msg.msgID, result, err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait replaced result timeout")
+	}
+
+	var stat QueueStat
+	for range 100 {
+		if stat = q.stats(); stat.Sent == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if stat.Sent != 1 || stat.Dropped != 2 {
+		t.Fatalf("stats mismatch: %+v", stat)
+	}
+}
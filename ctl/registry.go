@@ -0,0 +1,253 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCommandExists   = errors.New("command already registered")
+	ErrCommandBuiltin  = errors.New("builtin command can not be unregistered")
+	ErrInvalidCmdSpec  = errors.New("invalid command spec")
+	ErrUnknownCommand  = errors.New("unsupported command")
+	ErrInvalidArgument = errors.New("invalid command argument")
+)
+
+// ArgType 命令参数类型，用于客户端提示及服务端校验
+type ArgType string
+
+const (
+	ArgString   ArgType = "string"
+	ArgInt      ArgType = "int"
+	ArgUint     ArgType = "uint"
+	ArgBool     ArgType = "bool"
+	ArgDuration ArgType = "duration"
+	ArgJSON     ArgType = "json"
+)
+
+// ArgSpec 命令参数说明，参数均以字符串传递，按 Type 校验格式
+type ArgSpec struct {
+	Name     string
+	Type     ArgType
+	Help     string `json:",omitempty"`
+	Required bool   `json:",omitempty"`
+	// Positional 客户端可按位置传递的参数，按声明顺序依次对应
+	Positional bool     `json:",omitempty"`
+	Enum       []string `json:",omitempty"`
+}
+
+func (arg *ArgSpec) validate(v string) (err error) {
+	switch arg.Type {
+	case ArgInt:
+		_, err = strconv.ParseInt(v, 10, 64)
+	case ArgUint:
+		_, err = strconv.ParseUint(v, 10, 64)
+	case ArgBool:
+		_, err = strconv.ParseBool(v)
+	case ArgDuration:
+		_, err = time.ParseDuration(v)
+	}
+
+	if err == nil && len(arg.Enum) > 0 && !slices.Contains(arg.Enum, v) {
+		err = fmt.Errorf("must be one of %s", strings.Join(arg.Enum, "|"))
+	}
+
+	if err != nil {
+		return fmt.Errorf(
+			"%w: %s=%s, %v", ErrInvalidArgument, arg.Name, v, err,
+		)
+	}
+
+	return nil
+}
+
+// CommandHandler 命令处理函数，执行结果写入 result，
+// 返回错误且 result 未设置返回码时视为执行失败
+type CommandHandler func(
+	ctx context.Context, svr *CtlServer, cmd *Command, result *Result,
+) error
+
+// CommandSpec 命令定义，客户端可通过 schema 命令获取用于帮助、校验及补全
+type CommandSpec struct {
+	Name string
+	Help string    `json:",omitempty"`
+	Args []ArgSpec `json:",omitempty"`
+	// Role 执行命令所需的最低角色，未指定时仅 admin 可执行
+	Role Role
+	// ClientFree 无需 LatencyClient 运行即可执行
+	ClientFree bool `json:",omitempty"`
+	// Concurrent 只读或仅作用于连接会话的命令可与其他命令并发执行，其余命令串行执行
+	Concurrent bool `json:",omitempty"`
+	// Custom 由插件或嵌入程序注册的命令
+	Custom bool `json:",omitempty"`
+
+	Handler CommandHandler `json:"-"`
+}
+
+// Arg 按名称查找参数说明
+func (spec *CommandSpec) Arg(name string) *ArgSpec {
+	if idx := slices.IndexFunc(spec.Args, func(arg ArgSpec) bool {
+		return arg.Name == name
+	}); idx >= 0 {
+		return &spec.Args[idx]
+	}
+
+	return nil
+}
+
+// Positionals 可按位置传递的参数
+func (spec *CommandSpec) Positionals() []ArgSpec {
+	args := []ArgSpec{}
+
+	for _, arg := range spec.Args {
+		if arg.Positional {
+			args = append(args, arg)
+		}
+	}
+
+	return args
+}
+
+// Usage 命令用法，必选参数以 {} 表示，可选参数以 [] 表示
+func (spec *CommandSpec) Usage() string {
+	usage := []string{spec.Name}
+
+	for _, arg := range spec.Args {
+		v := arg.Name
+		if !arg.Positional {
+			v = fmt.Sprintf("--%s {%s}", arg.Name, arg.Type)
+		}
+
+		if arg.Required {
+			if arg.Positional {
+				v = "{" + v + "}"
+			}
+		} else {
+			v = "[" + v + "]"
+		}
+
+		usage = append(usage, v)
+	}
+
+	return strings.Join(usage, " ")
+}
+
+// Validate 校验必选参数及已声明参数的格式，未声明的参数由命令自行处理
+func (spec *CommandSpec) Validate(kwargs map[string]string) error {
+	for _, arg := range spec.Args {
+		v, exist := kwargs[arg.Name]
+
+		if !exist {
+			if arg.Required {
+				return fmt.Errorf(
+					"%w: %s required", ErrInvalidArgument, arg.Name,
+				)
+			}
+
+			continue
+		}
+
+		if err := arg.validate(v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (spec *CommandSpec) check() error {
+	if spec.Name == "" || strings.ContainsAny(spec.Name, " \t\n") {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidCmdSpec, spec.Name)
+	}
+
+	if spec.Handler == nil {
+		return fmt.Errorf("%w: %s has no handler", ErrInvalidCmdSpec, spec.Name)
+	}
+
+	names := map[string]bool{}
+	for _, arg := range spec.Args {
+		// timeout 为 CtlServer 保留的执行超时参数
+		if arg.Name == "" || arg.Name == "timeout" || names[arg.Name] {
+			return fmt.Errorf(
+				"%w: %s has invalid arg %q", ErrInvalidCmdSpec, spec.Name, arg.Name,
+			)
+		}
+
+		names[arg.Name] = true
+	}
+
+	if spec.Role == RoleNone {
+		spec.Role = RoleAdmin
+	}
+
+	return nil
+}
+
+var (
+	registeredCommands sync.Map
+)
+
+func registerCommand(spec CommandSpec) error {
+	if err := spec.check(); err != nil {
+		return err
+	}
+
+	if _, exist := registeredCommands.LoadOrStore(spec.Name, &spec); exist {
+		return fmt.Errorf("%w: %s", ErrCommandExists, spec.Name)
+	}
+
+	return nil
+}
+
+// RegisterCommand 注册自定义命令，已注册的同名命令（含内置命令）不可覆盖
+func RegisterCommand(spec CommandSpec) error {
+	spec.Custom = true
+
+	return registerCommand(spec)
+}
+
+// UnRegisterCommand 注销自定义命令
+func UnRegisterCommand(name string) error {
+	spec, exist := GetCommandSpec(name)
+	if !exist {
+		return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+	}
+
+	if !spec.Custom {
+		return fmt.Errorf("%w: %s", ErrCommandBuiltin, name)
+	}
+
+	registeredCommands.Delete(name)
+
+	return nil
+}
+
+func GetCommandSpec(name string) (*CommandSpec, bool) {
+	if v, exist := registeredCommands.Load(name); exist {
+		return v.(*CommandSpec), true
+	}
+
+	return nil, false
+}
+
+// CommandSpecs 已注册的全部命令，按名称排序
+func CommandSpecs() []*CommandSpec {
+	specs := []*CommandSpec{}
+
+	registeredCommands.Range(func(_, value any) bool {
+		specs = append(specs, value.(*CommandSpec))
+		return true
+	})
+
+	slices.SortFunc(specs, func(a, b *CommandSpec) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return specs
+}
@@ -0,0 +1,111 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync/atomic"
+	"testing"
+
+	"github.com/frozenpine/latency4go"
+)
+
+func TestCommandRegistry(t *testing.T) {
+	echo := func(
+		ctx context.Context, svr *CtlServer, cmd *Command, result *Result,
+	) error {
+		result.Message = cmd.KwArgs["text"]
+		return nil
+	}
+
+	if err := RegisterCommand(CommandSpec{
+		Name: "echo",
+		Help: "echo text back",
+		Args: []ArgSpec{
+			{Name: "text", Type: ArgString, Required: true, Positional: true},
+			{Name: "count", Type: ArgInt},
+		},
+		ClientFree: true,
+		Handler:    echo,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer UnRegisterCommand("echo")
+
+	for _, spec := range []CommandSpec{
+		{Name: "echo", Handler: echo},
+		{Name: "stop", Handler: echo},
+		{Name: "bad name", Handler: echo},
+		{Name: "nohandler"},
+		{Name: "reserved", Args: []ArgSpec{{Name: "timeout"}}, Handler: echo},
+	} {
+		if err := RegisterCommand(spec); err == nil {
+			t.Fatalf("invalid command registered: %s", spec.Name)
+		}
+	}
+
+	if err := UnRegisterCommand("stop"); !errors.Is(err, ErrCommandBuiltin) {
+		t.Fatalf("builtin command unregistered: %v", err)
+	}
+
+	spec, exist := GetCommandSpec("echo")
+	if !exist || !spec.Custom || spec.Role != RoleAdmin {
+		t.Fatalf("custom command spec mismatch: %+v", spec)
+	}
+
+	if err := spec.Validate(map[string]string{}); err == nil {
+		t.Fatal("required arg not validated")
+	}
+
+	if err := spec.Validate(map[string]string{
+		"text": "a", "count": "x",
+	}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("typed arg not validated: %v", err)
+	}
+
+	if !slices.Contains(supportedCommands(), "echo") {
+		t.Fatal("custom command not in supported commands")
+	}
+
+	svr, err := NewCtlServer(
+		t.Context(), (&CtlSvrHdlConfig{}).Tcp("127.0.0.1:0"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svr.instance = &atomic.Pointer[latency4go.LatencyClient]{}
+	go svr.runForever()
+	defer svr.cancel()
+
+	client, err := NewCtlTcpClient(
+		svr.handlers[0].(*CtlTcpHandler).listen.Addr().String() + "?reconnect=false",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	result, err := client.Call(t.Context(), &Command{
+		Name: "echo", KwArgs: map[string]string{"text": "hello"},
+	})
+	if err != nil || result.Rtn != 0 || result.Message != "hello" {
+		t.Fatalf("custom command failed: %+v, %+v", result, err)
+	}
+
+	if result, err = client.Call(
+		t.Context(), &Command{Name: "echo"},
+	); err != nil || result.Rtn == 0 {
+		t.Fatalf("invalid command args executed: %+v, %+v", result, err)
+	}
+
+	specs, err := NewCtlTypedClient(client).Schema(t.Context(), "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(specs) != 1 || specs[0].Name != "echo" ||
+		len(specs[0].Positionals()) != 1 {
+		t.Fatalf("command schema mismatch: %+v", specs)
+	}
+}
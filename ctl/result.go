@@ -18,6 +18,30 @@ const (
 	VKeyConfig         resultValueKey = "Config"
 	VKeyPlugin         resultValueKey = "Plugins"
 	VKeyHandler        resultValueKey = "Handlers"
+	VKeyAudit          resultValueKey = "Audit"
+	VKeySubscription   resultValueKey = "Subscription"
+	VKeyQueue          resultValueKey = "Queues"
+	VKeySession        resultValueKey = "Sessions"
+	VKeySchema         resultValueKey = "Schema"
+	VKeyPluginName     resultValueKey = "PluginName"
+	VKeySeat           resultValueKey = "Seats"
+	VKeyPriority       resultValueKey = "Priority"
+	VKeyPin            resultValueKey = "Pins"
+	VKeyHistory        resultValueKey = "History"
+	VKeyRollback       resultValueKey = "Rollback"
+	VKeyProposal       resultValueKey = "Proposals"
+	VKeyApproval       resultValueKey = "Approvals"
+)
+
+const (
+	// RtnDenied 连接角色无权执行命令时的返回码
+	RtnDenied = 403
+	// RtnTimeout 命令执行超时的返回码
+	RtnTimeout = 408
+	// RtnTooLarge 命令结果超出连接最大帧长度的返回码
+	RtnTooLarge = 413
+	// RtnCancelled 命令被 cancel 命令取消的返回码
+	RtnCancelled = 499
 )
 
 type values map[resultValueKey]any
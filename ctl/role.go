@@ -0,0 +1,89 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+var (
+	ErrInvalidRole      = errors.New("invalid role")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+type Role uint8
+
+const (
+	RoleNone Role = iota
+	RoleViewer
+	RoleOperator
+	RoleAdmin
+)
+
+var roleNames = [...]string{"none", "viewer", "operator", "admin"}
+
+func (r Role) String() string {
+	if int(r) < len(roleNames) {
+		return roleNames[r]
+	}
+
+	return fmt.Sprintf("Role(%d)", r)
+}
+
+func (r Role) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Role) UnmarshalText(v []byte) error {
+	role, err := ParseRole(string(v))
+	if err != nil {
+		return err
+	}
+
+	*r = role
+	return nil
+}
+
+func ParseRole(v string) (Role, error) {
+	for idx, name := range roleNames {
+		if strings.EqualFold(v, name) {
+			return Role(idx), nil
+		}
+	}
+
+	return RoleNone, fmt.Errorf("%w: %s", ErrInvalidRole, v)
+}
+
+// commandRole 命令执行所需的最低角色，未注册的命令仅 admin 可执行
+func commandRole(name string) Role {
+	if spec, exist := GetCommandSpec(name); exist {
+		return spec.Role
+	}
+
+	return RoleAdmin
+}
+
+// roleFile 角色文件格式，可与认证密钥文件合并:
+//
+//	[roles]
+//	ops = "operator"
+type roleFile struct {
+	Roles map[string]Role `toml:"roles"`
+}
+
+func loadRoles(path string) (map[string]Role, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var roles roleFile
+	if err = toml.Unmarshal(data, &roles); err != nil {
+		return nil, err
+	}
+
+	return roles.Roles, nil
+}
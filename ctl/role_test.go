@@ -0,0 +1,49 @@
+package ctl
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionAuthorize(t *testing.T) {
+	roleFile := filepath.Join(t.TempDir(), "roles.toml")
+	if err := os.WriteFile(
+		roleFile, []byte("[roles]\nops = \"operator\"\nroot = \"admin\"\n"), 0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	hdl := ctlBaseHandler{hdlName: "test"}
+	if err := hdl.parseBaseOptions(url.Values{
+		"role": {"viewer"}, "roles": {roleFile},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		identity string
+		cmdName  string
+		denied   bool
+	}{
+		{"", "state", false},
+		{"", "period", true},
+		{"ops", "suspend", false},
+		{"ops", "stop", true},
+		{"root", "plugin", false},
+		{"root", "unknown", false},
+		{"ops", "unknown", true},
+	} {
+		err := hdl.newSession("remote", c.identity).authorize(c.cmdName)
+
+		if denied := errors.Is(err, ErrPermissionDenied); denied != c.denied {
+			t.Errorf("%s@%s: denied %v, expect %v", c.identity, c.cmdName, denied, c.denied)
+		}
+	}
+
+	if err := hdl.parseBaseOptions(url.Values{"role": {"root"}}); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("invalid role parsed: %+v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package ctl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+	"github.com/frozenpine/latency4go/libs"
+)
+
+// defaultRollbackHold rollback 后默认暂停周期上报的时长
+const defaultRollbackHold = time.Minute * 10
+
+// pluginTargets 命令参数中逗号分隔的目标插件，未指定时为全部已加载插件
+func pluginTargets(v string) []string {
+	plugins := splitList(v)
+
+	if len(plugins) == 0 {
+		libs.RangePlugins(func(name string, _ *libs.PluginContainer) error {
+			plugins = append(plugins, name)
+			return nil
+		})
+	}
+
+	return plugins
+}
+
+func cmdHistory(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+	history := map[string][]*latency4go.State{}
+
+	for _, name := range pluginTargets(cmd.KwArgs["plugin"]) {
+		history[name] = client.GetHistory(name)
+	}
+
+	result.Values[VKeyHistory] = history
+	result.Message = "get report history finished"
+
+	return nil
+}
+
+func cmdRollback(_ context.Context, svr *CtlServer, cmd *Command, result *Result) error {
+	client := cmd.client
+
+	steps := 1
+	if v, exist := cmd.KwArgs["steps"]; exist {
+		var err error
+		if steps, err = strconv.Atoi(v); err != nil || steps < 1 {
+			result.Rtn = 1
+			result.Message = fmt.Sprintf("invalid rollback steps: %s", v)
+			return fmt.Errorf("%w: invalid rollback steps", ErrInvalidArgument)
+		}
+	}
+
+	hold := svr.rollbackHold
+	if v, exist := cmd.KwArgs["hold"]; exist {
+		var err error
+		if hold, err = time.ParseDuration(v); err != nil || hold < 0 {
+			result.Rtn = 1
+			result.Message = fmt.Sprintf("invalid rollback hold: %s", v)
+			return fmt.Errorf("%w: invalid rollback hold", ErrInvalidArgument)
+		}
+	}
+
+	plugins := pluginTargets(cmd.KwArgs["plugin"])
+	if len(plugins) == 0 {
+		result.Rtn = 1
+		result.Message = "no plugin loaded"
+		return nil
+	}
+
+	rollback := map[string][]string{}
+	errs := []error{}
+
+	for _, name := range plugins {
+		state, err := client.Rollback(name, steps, hold)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+
+			// 历史不足时无目标状态，上报失败时目标状态未生效
+			message := fmt.Sprintf("%s rollback %d steps failed: %v", name, steps, err)
+			if state != nil {
+				message = fmt.Sprintf(
+					"%s rollback %d steps to %v failed: %v",
+					name, steps, state.AddrList, err,
+				)
+			}
+			svr.alert(slog.LevelError, "rollback", message)
+
+			continue
+		}
+
+		rollback[name] = state.AddrList
+
+		svr.alert(slog.LevelWarn, "rollback", fmt.Sprintf(
+			"%s rolled back %d steps to %v, report held for %s",
+			name, steps, state.AddrList, hold,
+		))
+	}
+
+	err := errors.Join(errs...)
+
+	result.Values[VKeyRollback] = rollback
+	result.Values[VKeyPin] = client.GetPins()
+
+	if err != nil {
+		result.Rtn = 1
+		result.Message = fmt.Sprintf(
+			"rollback failed: %s", strings.ReplaceAll(err.Error(), "\n", "; "),
+		)
+	} else {
+		result.Message = "rollback finished"
+	}
+
+	return err
+}
@@ -0,0 +1,104 @@
+package ctl
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+	"github.com/frozenpine/msgqueue/core"
+)
+
+func TestRollbackAlert(t *testing.T) {
+	svr, err := NewCtlServer(t.Context(), &CtlSvrHdlConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svr.Stop()
+
+	_, notify := svr.broadcast.Subscribe("test", core.Quick)
+
+	var broken atomic.Bool
+	client := &latency4go.LatencyClient{}
+	if err := client.AddReporter("test", func(*latency4go.State) error {
+		if broken.Load() {
+			return errors.New("broken reporter")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, addr := range []string{"a", "b"} {
+		if err := client.Push(
+			&latency4go.State{AddrList: []string{addr}}, time.Time{}, "test",
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nextAlert := func() *Alert {
+		t.Helper()
+
+		for {
+			select {
+			case msg := <-notify:
+				if msg.GetTopic() != TopicAlert {
+					continue
+				}
+
+				event, err := msg.GetEvent()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				alert, err := GetEventData[Alert](event)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return alert
+			case <-time.After(time.Second * 5):
+				t.Fatal("wait alert timeout")
+			}
+		}
+	}
+
+	// 上报失败时不告警回退成功，改为 error 级别的失败告警
+	broken.Store(true)
+
+	result := &Result{Values: make(values)}
+	if err := cmdRollback(t.Context(), svr, &Command{
+		KwArgs: map[string]string{"plugin": "test", "hold": "0s"},
+		client: client,
+	}, result); err == nil || result.Rtn == 0 {
+		t.Fatalf("failed rollback succeeded: %+v", result)
+	}
+
+	if rollback := result.Values[VKeyRollback].(map[string][]string); len(rollback) != 0 {
+		t.Fatalf("failed rollback reported: %+v", rollback)
+	}
+
+	if alert := nextAlert(); alert.Level != slog.LevelError ||
+		!strings.Contains(alert.Message, "failed") {
+		t.Fatalf("failed rollback alert mismatch: %+v", alert)
+	}
+
+	broken.Store(false)
+
+	result = &Result{Values: make(values)}
+	if err := cmdRollback(t.Context(), svr, &Command{
+		KwArgs: map[string]string{"plugin": "test", "hold": "0s"},
+		client: client,
+	}, result); err != nil || result.Rtn != 0 {
+		t.Fatalf("rollback failed: %+v, %v", result, err)
+	}
+
+	if alert := nextAlert(); alert.Level != slog.LevelWarn ||
+		!strings.Contains(alert.Message, "rolled back 1 steps to [a]") {
+		t.Fatalf("rollback alert mismatch: %+v", alert)
+	}
+}
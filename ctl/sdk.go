@@ -0,0 +1,540 @@
+package ctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+	"github.com/frozenpine/latency4go/libs"
+)
+
+// CommandError 命令执行失败（Rtn 非 0）时返回的错误
+type CommandError struct {
+	Rtn     int
+	CmdName string
+	Message string
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s failed[%d]: %s", e.CmdName, e.Rtn, e.Message)
+}
+
+func (e *CommandError) Unwrap() error {
+	if e.Rtn == RtnDenied {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// GetResultValue 从 Result.Values 中解析指定键的强类型值，键不存在时 exist 为 false
+func GetResultValue[T any](r *Result, key resultValueKey) (v T, exist bool, err error) {
+	if r == nil {
+		return
+	}
+
+	value, exist := r.Values[key]
+	if !exist {
+		return
+	}
+
+	switch raw := value.(type) {
+	case json.RawMessage:
+		err = json.Unmarshal(raw, &v)
+	case T:
+		v = raw
+	default:
+		// 服务端本地构造的 Result 中值为原始类型，经 JSON 转换统一处理
+		var data []byte
+		if data, err = json.Marshal(value); err == nil {
+			err = json.Unmarshal(data, &v)
+		}
+	}
+
+	if err != nil {
+		err = fmt.Errorf("%w: %s %+v", ErrInvalidMsgData, key, err)
+	}
+
+	return
+}
+
+// CtlInfo info / start 命令返回的 LatencyClient 运行信息
+type CtlInfo struct {
+	State    *latency4go.State
+	Interval time.Duration
+	Handlers []string
+	Plugins  []*libs.PluginContainer
+	Queues   []QueueStat
+	Pins     map[string]time.Time
+}
+
+func NewCtlInfo(r *Result) (*CtlInfo, error) {
+	info := CtlInfo{}
+
+	var err error
+	if info.State, _, err = GetResultValue[*latency4go.State](
+		r, VKeyState,
+	); err != nil {
+		return nil, err
+	}
+
+	if info.Interval, _, err = GetResultValue[time.Duration](
+		r, VKeyInterval,
+	); err != nil {
+		return nil, err
+	}
+
+	if info.Handlers, _, err = GetResultValue[[]string](
+		r, VKeyHandler,
+	); err != nil {
+		return nil, err
+	}
+
+	if info.Plugins, _, err = GetResultValue[[]*libs.PluginContainer](
+		r, VKeyPlugin,
+	); err != nil {
+		return nil, err
+	}
+
+	if info.Queues, _, err = GetResultValue[[]QueueStat](
+		r, VKeyQueue,
+	); err != nil {
+		return nil, err
+	}
+
+	if info.Pins, _, err = GetResultValue[map[string]time.Time](
+		r, VKeyPin,
+	); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+// CtlTypedClient 在 CtlClient 之上提供强类型的命令接口，供嵌入 ctl 包的 Go 服务使用
+type CtlTypedClient struct {
+	CtlClient
+}
+
+func NewCtlTypedClient(client CtlClient) *CtlTypedClient {
+	return &CtlTypedClient{CtlClient: client}
+}
+
+func (c *CtlTypedClient) call(
+	ctx context.Context, name string, kwargs map[string]string,
+) (*Result, error) {
+	result, err := c.Call(ctx, &Command{Name: name, KwArgs: kwargs})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Rtn != 0 {
+		return result, &CommandError{
+			Rtn:     result.Rtn,
+			CmdName: result.CmdName,
+			Message: result.Message,
+		}
+	}
+
+	return result, nil
+}
+
+func (c *CtlTypedClient) Info(ctx context.Context) (*CtlInfo, error) {
+	result, err := c.call(ctx, "info", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCtlInfo(result)
+}
+
+func (c *CtlTypedClient) State(ctx context.Context) (*latency4go.State, error) {
+	result, err := c.call(ctx, "state", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _, err := GetResultValue[*latency4go.State](result, VKeyState)
+	return state, err
+}
+
+// Query 以临时配置执行一次性查询，cfg 为空时使用服务端当前配置
+func (c *CtlTypedClient) Query(
+	ctx context.Context, cfg *latency4go.QueryConfig,
+) (*latency4go.State, error) {
+	kwargs := map[string]string{}
+
+	if cfg != nil {
+		data, err := json.Marshal(cfg)
+		if err != nil {
+			return nil, err
+		}
+		kwargs["config"] = string(data)
+	}
+
+	result, err := c.call(ctx, "query", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _, err := GetResultValue[*latency4go.State](result, VKeyState)
+	return state, err
+}
+
+// SetConfig 以完整的 QueryConfig 替换服务端查询配置，返回生效后的配置
+func (c *CtlTypedClient) SetConfig(
+	ctx context.Context, cfg *latency4go.QueryConfig,
+) (*latency4go.QueryConfig, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.UpdateConfig(ctx, map[string]string{"config": string(data)})
+}
+
+// UpdateConfig 按键值修改服务端查询配置，可用键同 config 命令参数
+func (c *CtlTypedClient) UpdateConfig(
+	ctx context.Context, kwargs map[string]string,
+) (*latency4go.QueryConfig, error) {
+	result, err := c.call(ctx, "config", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := GetResultValue[*latency4go.QueryConfig](result, VKeyConfig)
+	return cfg, err
+}
+
+// Period 修改查询周期，返回修改前的周期
+func (c *CtlTypedClient) Period(
+	ctx context.Context, interval time.Duration,
+) (time.Duration, error) {
+	result, err := c.call(ctx, "period", map[string]string{
+		"interval": interval.String(),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	origin, _, err := GetResultValue[time.Duration](result, VKeyIntervalOrigin)
+	return origin, err
+}
+
+func (c *CtlTypedClient) Suspend(ctx context.Context) error {
+	_, err := c.call(ctx, "suspend", nil)
+	return err
+}
+
+func (c *CtlTypedClient) Resume(ctx context.Context) error {
+	_, err := c.call(ctx, "resume", nil)
+	return err
+}
+
+// StartLatency 启动服务端 LatencyClient，未指定的参数沿用上次运行的配置
+func (c *CtlTypedClient) StartLatency(
+	ctx context.Context, kwargs map[string]string,
+) (*CtlInfo, error) {
+	result, err := c.call(ctx, "start", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCtlInfo(result)
+}
+
+func (c *CtlTypedClient) StopLatency(ctx context.Context) error {
+	_, err := c.call(ctx, "stop", nil)
+	return err
+}
+
+// LoadPlugin 从服务端 libDir 目录加载插件并以 config 配置初始化
+func (c *CtlTypedClient) LoadPlugin(
+	ctx context.Context, name, libDir, config string,
+) error {
+	_, err := c.call(ctx, "plugin", map[string]string{
+		"plugin": name,
+		"lib":    libDir,
+		"config": config,
+	})
+	return err
+}
+
+func (c *CtlTypedClient) UnloadPlugin(ctx context.Context, name string) error {
+	_, err := c.call(ctx, "unplugin", map[string]string{
+		"plugin": name,
+	})
+	return err
+}
+
+// Audit 查询最近 count 条审计记录，identity、name 为空时不过滤
+func (c *CtlTypedClient) Audit(
+	ctx context.Context, count int, identity, name string,
+) ([]*AuditEntry, error) {
+	kwargs := map[string]string{}
+
+	if count > 0 {
+		kwargs["count"] = strconv.Itoa(count)
+	}
+	if identity != "" {
+		kwargs["identity"] = identity
+	}
+	if name != "" {
+		kwargs["name"] = name
+	}
+
+	result, err := c.call(ctx, "audit", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, _, err := GetResultValue[[]*AuditEntry](result, VKeyAudit)
+	return entries, err
+}
+
+// Subscribe 订阅广播主题，返回当前会话的全部订阅
+func (c *CtlTypedClient) Subscribe(
+	ctx context.Context, topic Topic, opt SubOption,
+) (map[Topic]SubOption, error) {
+	kwargs := map[string]string{"topic": string(topic)}
+
+	if opt.K > 0 {
+		kwargs["k"] = strconv.Itoa(opt.K)
+	}
+	if opt.Changed {
+		kwargs["changed"] = "true"
+	}
+	if opt.Level != 0 {
+		kwargs["level"] = opt.Level.String()
+	}
+
+	result, err := c.call(ctx, "subscribe", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, _, err := GetResultValue[map[Topic]SubOption](result, VKeySubscription)
+	return subs, err
+}
+
+func (c *CtlTypedClient) Unsubscribe(
+	ctx context.Context, topic Topic,
+) (map[Topic]SubOption, error) {
+	result, err := c.call(ctx, "unsubscribe", map[string]string{
+		"topic": string(topic),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	subs, _, err := GetResultValue[map[Topic]SubOption](result, VKeySubscription)
+	return subs, err
+}
+
+// Sessions 查询服务端全部已连接的客户端会话
+func (c *CtlTypedClient) Sessions(ctx context.Context) ([]SessionInfo, error) {
+	result, err := c.call(ctx, "sessions", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, _, err := GetResultValue[[]SessionInfo](result, VKeySession)
+	return sessions, err
+}
+
+// Kick 断开 remote 对应的客户端会话，handler 为空时在全部 Handler 中查找
+func (c *CtlTypedClient) Kick(ctx context.Context, remote, handler string) error {
+	kwargs := map[string]string{"remote": remote}
+
+	if handler != "" {
+		kwargs["handler"] = handler
+	}
+
+	_, err := c.call(ctx, "kick", kwargs)
+	return err
+}
+
+// Seats 查询插件对应交易系统的席位列表
+func (c *CtlTypedClient) Seats(ctx context.Context, plugin string) ([]libs.Seat, error) {
+	result, err := c.call(ctx, "seats", map[string]string{"plugin": plugin})
+	if err != nil {
+		return nil, err
+	}
+
+	seats, _, err := GetResultValue[[]libs.Seat](result, VKeySeat)
+	return seats, err
+}
+
+// Priority 查询插件对应交易系统当前的席位优先级，每级为席位序号列表
+func (c *CtlTypedClient) Priority(ctx context.Context, plugin string) ([][]int, error) {
+	result, err := c.call(ctx, "priority", map[string]string{"plugin": plugin})
+	if err != nil {
+		return nil, err
+	}
+
+	priority, _, err := GetResultValue[[][]int](result, VKeyPriority)
+	return priority, err
+}
+
+// Push 跳过延迟查询向插件推送指定的前置地址顺序，plugins 为空时推送至全部插件，
+// until 非零时锁定插件至该时间
+func (c *CtlTypedClient) Push(
+	ctx context.Context, addrs []string, plugins []string, until time.Time,
+) (*latency4go.State, error) {
+	return c.push(ctx, map[string]string{"addrs": strings.Join(addrs, ",")}, plugins, until)
+}
+
+// Reorder 按当前状态中的排名（从 1 开始）重新排序后推送，未指定的前置按原顺序追加
+func (c *CtlTypedClient) Reorder(
+	ctx context.Context, order []int, plugins []string, until time.Time,
+) (*latency4go.State, error) {
+	return c.push(ctx, map[string]string{
+		"order": strings.Join(latency4go.ConvertSlice(order, strconv.Itoa), ","),
+	}, plugins, until)
+}
+
+func (c *CtlTypedClient) push(
+	ctx context.Context, kwargs map[string]string,
+	plugins []string, until time.Time,
+) (*latency4go.State, error) {
+	if len(plugins) > 0 {
+		kwargs["plugin"] = strings.Join(plugins, ",")
+	}
+
+	if !until.IsZero() {
+		kwargs["until"] = until.Format(time.RFC3339)
+	}
+
+	result, err := c.call(ctx, "push", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	state, _, err := GetResultValue[*latency4go.State](result, VKeyState)
+	return state, err
+}
+
+// Unpin 解除插件锁定，恢复周期上报，plugins 为空时解除全部锁定
+func (c *CtlTypedClient) Unpin(ctx context.Context, plugins ...string) error {
+	kwargs := map[string]string{}
+
+	if len(plugins) > 0 {
+		kwargs["plugin"] = strings.Join(plugins, ",")
+	}
+
+	_, err := c.call(ctx, "unpin", kwargs)
+	return err
+}
+
+// History 查询插件最近已成功上报的状态，plugins 为空时查询全部插件
+func (c *CtlTypedClient) History(
+	ctx context.Context, plugins ...string,
+) (map[string][]*latency4go.State, error) {
+	result, err := c.call(ctx, "history", map[string]string{
+		"plugin": strings.Join(plugins, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history, _, err := GetResultValue[map[string][]*latency4go.State](result, VKeyHistory)
+	return history, err
+}
+
+// Rollback 将插件 steps 次上报前的状态重新上报，hold 为 0 时使用服务端默认的暂停时长，
+// 返回各插件回退后的前置地址顺序
+func (c *CtlTypedClient) Rollback(
+	ctx context.Context, steps int, hold time.Duration, plugins ...string,
+) (map[string][]string, error) {
+	kwargs := map[string]string{"steps": strconv.Itoa(steps)}
+
+	if hold > 0 {
+		kwargs["hold"] = hold.String()
+	}
+
+	if len(plugins) > 0 {
+		kwargs["plugin"] = strings.Join(plugins, ",")
+	}
+
+	result, err := c.call(ctx, "rollback", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	rollback, _, err := GetResultValue[map[string][]string](result, VKeyRollback)
+	return rollback, err
+}
+
+// SetApproval 设置插件的审批模式，ttl 为提案过期时长，为 0 时使用服务端默认值，
+// off 为 true 时关闭审批模式
+func (c *CtlTypedClient) SetApproval(
+	ctx context.Context, plugin string, ttl time.Duration, off bool,
+) error {
+	kwargs := map[string]string{"plugin": plugin}
+
+	if ttl > 0 {
+		kwargs["ttl"] = ttl.String()
+	}
+
+	if off {
+		kwargs["off"] = "true"
+	}
+
+	_, err := c.call(ctx, "approval", kwargs)
+	return err
+}
+
+// Proposals 查询待审批的前置顺序提案
+func (c *CtlTypedClient) Proposals(ctx context.Context) ([]*latency4go.Proposal, error) {
+	result, err := c.call(ctx, "proposals", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	proposals, _, err := GetResultValue[[]*latency4go.Proposal](result, VKeyProposal)
+	return proposals, err
+}
+
+// Approve 批准提案，服务端经插件上报提案中的前置顺序
+func (c *CtlTypedClient) Approve(ctx context.Context, id uint64) error {
+	_, err := c.call(ctx, "approve", map[string]string{
+		"id": strconv.FormatUint(id, 10),
+	})
+	return err
+}
+
+// Reject 拒绝提案，插件保持当前的前置顺序
+func (c *CtlTypedClient) Reject(ctx context.Context, id uint64) error {
+	_, err := c.call(ctx, "reject", map[string]string{
+		"id": strconv.FormatUint(id, 10),
+	})
+	return err
+}
+
+// Schema 查询服务端已注册命令的定义，name 为空时返回全部命令
+func (c *CtlTypedClient) Schema(ctx context.Context, name string) ([]*CommandSpec, error) {
+	kwargs := map[string]string{}
+
+	if name != "" {
+		kwargs["name"] = name
+	}
+
+	result, err := c.call(ctx, "schema", kwargs)
+	if err != nil {
+		return nil, err
+	}
+
+	specs, _, err := GetResultValue[[]*CommandSpec](result, VKeySchema)
+	return specs, err
+}
+
+// Execute 执行自定义命令，返回原始执行结果
+func (c *CtlTypedClient) Execute(
+	ctx context.Context, name string, kwargs map[string]string,
+) (*Result, error) {
+	return c.call(ctx, name, kwargs)
+}
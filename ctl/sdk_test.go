@@ -0,0 +1,55 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetResultValue(t *testing.T) {
+	local := &Result{
+		CmdName: "period",
+		Values: values{
+			VKeyInterval:       time.Minute,
+			VKeyIntervalOrigin: time.Second * 30,
+			VKeyHandler:        []string{"tcp://127.0.0.1:45678"},
+		},
+	}
+
+	data, err := json.Marshal(local)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var remote Result
+	if err = json.Unmarshal(data, &remote); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, r := range []*Result{local, &remote} {
+		interval, exist, err := GetResultValue[time.Duration](r, VKeyInterval)
+		if err != nil || !exist || interval != time.Minute {
+			t.Errorf("get interval failed: %v %v %+v", interval, exist, err)
+		}
+
+		handlers, _, err := GetResultValue[[]string](r, VKeyHandler)
+		if err != nil || len(handlers) != 1 {
+			t.Errorf("get handlers failed: %v %+v", handlers, err)
+		}
+
+		if _, exist, _ = GetResultValue[int](r, VKeyState); exist {
+			t.Error("missing key exists")
+		}
+
+		if _, _, err = GetResultValue[[]string](r, VKeyInterval); !errors.Is(
+			err, ErrInvalidMsgData,
+		) {
+			t.Errorf("invalid value type decoded: %+v", err)
+		}
+	}
+
+	if err := (&CommandError{Rtn: RtnDenied}); !errors.Is(err, ErrPermissionDenied) {
+		t.Error("denied command error not match")
+	}
+}
@@ -32,6 +32,20 @@ type CtlServer struct {
 	stopOnce  sync.Once
 	handlers  []Handler
 	broadcast channel.MemoChannel[*Message]
+	auditor   *ctlAuditor
+	logs      *LogHandler
+
+	// inflight 执行中的命令，用于取消命令
+	inflight sync.Map
+	// cmdLock 串行执行变更 LatencyClient 及插件的命令
+	cmdLock sync.Mutex
+
+	// historySize LatencyClient 每个 reporter 保留的已上报状态数
+	historySize int
+	// rollbackHold rollback 命令未指定 hold 时暂停周期上报的时长
+	rollbackHold time.Duration
+	// approvals 开启审批模式的插件及提案过期时长，LatencyClient 重新启动后重新设置
+	approvals sync.Map
 
 	queryCfg      *latency4go.QueryConfig
 	queryInterval time.Duration
@@ -57,6 +71,18 @@ func NewCtlServer(
 
 		svr.broadcast.Init(svr.ctx, "broadcast", nil)
 
+		if cfg.auditPath != "" {
+			if svr.auditor, err = newCtlAuditor(cfg.auditPath); err != nil {
+				err = errors.Join(ErrInitCtlServer, err)
+				return
+			}
+
+			slog.Info(
+				"ctl command audit enabled",
+				slog.String("path", cfg.auditPath),
+			)
+		}
+
 		for _, hdl := range cfg.handlers {
 			if hdl == nil {
 				err = errors.New("nil ctl handler")
@@ -88,19 +114,82 @@ func NewCtlServer(
 			svr.handlers = append(svr.handlers, hdl)
 
 		}
+
+		if cfg.logHandler != nil {
+			svr.logs = cfg.logHandler
+			svr.logs.attach(svr)
+		}
+
+		svr.historySize = cfg.historySize
+		svr.rollbackHold = cfg.rollbackHold
+		if svr.rollbackHold <= 0 {
+			svr.rollbackHold = defaultRollbackHold
+		}
+
+		for name, ttl := range cfg.approvals {
+			if ttl > 0 {
+				svr.approvals.Store(name, ttl)
+			}
+		}
 	})
 
 	return
 }
 
+// LatencyClient 当前运行的 LatencyClient，未运行时为 nil，
+// 命令处理函数应使用 Command.Client 获取执行时加载的客户端
+func (svr *CtlServer) LatencyClient() *latency4go.LatencyClient {
+	if svr.instance == nil {
+		return nil
+	}
+
+	return svr.instance.Load()
+}
+
 func (svr *CtlServer) GetLatestState() *latency4go.State {
 	return svr.instance.Load().GetLastState()
 }
 
+// applyApprovals 将保存的审批模式设置至 client 中已注册的 reporter，names 为空时设置全部，
+// 尚未注册的插件在 plugin 命令加载时设置
+func (svr *CtlServer) applyApprovals(
+	client *latency4go.LatencyClient, names ...string,
+) {
+	svr.approvals.Range(func(key, value any) bool {
+		name := key.(string)
+		if len(names) > 0 && !slices.Contains(names, name) {
+			return true
+		}
+
+		if err := client.SetApproval(name, value.(time.Duration)); err != nil {
+			slog.Info(
+				"plugin approval mode deferred",
+				slog.String("plugin", name),
+				slog.Any("error", err),
+			)
+		}
+
+		return true
+	})
+}
+
 func (svr *CtlServer) connectReporter() error {
-	return svr.instance.Load().AddReporter(
+	client := svr.instance.Load()
+
+	client.OnProposal(func(p *latency4go.Proposal) {
+		svr.publishEvent(TopicProposal, p)
+	})
+
+	return client.AddReporter(
 		"controller",
 		func(state *latency4go.State) error {
+			if len(state.LatencyList) == 0 {
+				svr.alert(
+					slog.LevelWarn, "controller",
+					"latency query returned no front",
+				)
+			}
+
 			data, err := json.Marshal(state)
 
 			if err != nil {
@@ -111,7 +200,9 @@ func (svr *CtlServer) connectReporter() error {
 			} else {
 				if err = svr.broadcast.Publish(&Message{
 					msgType: MsgBroadCast,
+					topic:   TopicState,
 					data:    data,
+					state:   state,
 				}, time.Second*5); err != nil {
 					slog.Error("ctl reporter publish priorities timeout")
 				} else {
@@ -127,6 +218,35 @@ func (svr *CtlServer) connectReporter() error {
 	)
 }
 
+// publishEvent 向订阅 topic 主题的连接广播事件
+func (svr *CtlServer) publishEvent(topic Topic, v any) {
+	msg, err := newEventMessage(topic, v)
+	if err != nil {
+		slog.Error(
+			"create event message failed",
+			slog.Any("error", err),
+			slog.String("topic", string(topic)),
+		)
+		return
+	}
+
+	if err = svr.broadcast.Publish(msg, time.Second*5); err != nil {
+		slog.Error(
+			"publish event message failed",
+			slog.Any("error", err),
+			slog.String("topic", string(topic)),
+		)
+	}
+}
+
+func (svr *CtlServer) alert(level slog.Level, source, message string) {
+	svr.publishEvent(TopicAlert, &Alert{
+		Level:   level,
+		Source:  source,
+		Message: message,
+	})
+}
+
 func (svr *CtlServer) Start(
 	instance *atomic.Pointer[latency4go.LatencyClient],
 ) (err error) {
@@ -139,6 +259,11 @@ func (svr *CtlServer) Start(
 	svr.startOnce.Do(func() {
 		svr.instance = instance
 
+		if client := instance.Load(); client != nil {
+			client.SetHistorySize(svr.historySize)
+			svr.applyApprovals(client)
+		}
+
 		if err = svr.connectReporter(); err != nil {
 			err = errors.Join(ErrInitCtlServer, err)
 
@@ -153,6 +278,10 @@ func (svr *CtlServer) Start(
 
 func (svr *CtlServer) Stop() {
 	svr.stopOnce.Do(func() {
+		if svr.logs != nil {
+			svr.logs.detach(svr)
+		}
+
 		svr.broadcast.Release()
 
 		for _, hdl := range svr.handlers {
@@ -160,6 +289,15 @@ func (svr *CtlServer) Stop() {
 		}
 
 		svr.handlers = nil
+
+		if svr.auditor != nil {
+			if err := svr.auditor.close(); err != nil {
+				slog.Error(
+					"close audit log failed",
+					slog.Any("error", err),
+				)
+			}
+		}
 	})
 }
 
@@ -190,12 +328,84 @@ func (svr *CtlServer) read() []reflect.SelectCase {
 	)...)
 }
 
-func (svr *CtlServer) write(idx int, msg *Message) error {
-	if idx > len(svr.handlers) {
-		return errors.New("handler not exists")
+// execute 校验会话权限并执行命令，执行结果写入审计日志
+func (svr *CtlServer) execute(
+	ctx context.Context, msg *Message, cmd *Command,
+) (result *Result, err error) {
+	var before *latency4go.QueryConfig
+	if client := svr.instance.Load(); client != nil && auditConfigCommands[cmd.Name] {
+		before = client.GetConfig()
+	}
+
+	cmd.session = msg.session
+	msg.session.touch(cmd.Name)
+
+	if err = msg.session.authorize(cmd.Name); err != nil {
+		slog.Warn(
+			"command denied",
+			slog.Any("error", err),
+			slog.String("handler", msg.session.handler),
+			slog.String("remote", msg.session.remote),
+			slog.String("identity", msg.session.identity),
+		)
+
+		result = &Result{
+			Rtn:     RtnDenied,
+			Message: err.Error(),
+			CmdName: cmd.Name,
+		}
+	} else {
+		result, err = cmd.Execute(ctx, svr)
 	}
 
-	return svr.handlers[idx-1].Publish(msg, time.Second*3)
+	svr.audit(msg, cmd, result, before)
+
+	return
+}
+
+func (svr *CtlServer) audit(
+	msg *Message, cmd *Command, result *Result,
+	before *latency4go.QueryConfig,
+) {
+	if svr.auditor == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Timestamp: time.Now(),
+		MsgID:     msg.msgID,
+		Role:      RoleAdmin,
+		Command:   cmd.Name,
+		KwArgs:    cmd.KwArgs,
+	}
+
+	if sess := msg.session; sess != nil {
+		entry.Handler = sess.handler
+		entry.Remote = sess.remote
+		entry.Identity = sess.identity
+		entry.Role = sess.role
+	}
+
+	if result != nil {
+		entry.Rtn = result.Rtn
+		entry.Message = result.Message
+	}
+
+	if auditConfigCommands[cmd.Name] && entry.Rtn == 0 {
+		entry.Before = before
+
+		if client := svr.instance.Load(); client != nil {
+			entry.After = client.GetConfig()
+		}
+	}
+
+	if err := svr.auditor.record(&entry); err != nil {
+		slog.Error(
+			"write audit log failed",
+			slog.Any("error", err),
+			slog.Any("entry", entry),
+		)
+	}
 }
 
 func (svr *CtlServer) runForever() {
@@ -214,9 +424,8 @@ func (svr *CtlServer) runForever() {
 				)
 			}
 
-			for _, hdl := range svr.handlers {
-				hdl.Release()
-			}
+			// Handler 由 Stop 统一释放
+			return
 		default:
 			idx, recv, ok := reflect.Select(svr.read())
 
@@ -249,7 +458,7 @@ func (svr *CtlServer) runForever() {
 
 			cmd, err := msg.GetCommand()
 
-			if err != nil {
+			if err != nil || cmd == nil {
 				slog.Error(
 					"receive a not commnd message",
 					slog.Any("msg", recv.Interface()),
@@ -257,41 +466,8 @@ func (svr *CtlServer) runForever() {
 				continue
 			}
 
-			result, err := cmd.Execute(svr)
-			if err != nil {
-				slog.Error(
-					"execute command failed",
-					slog.Any("error", err),
-					slog.Any("msg", msg),
-				)
-			}
-			if result == nil {
-				continue
-			}
-
-			data, err := json.Marshal(result)
-			if err != nil {
-				slog.Error(
-					"marshal result failed",
-					slog.Any("error", err),
-					slog.Any("msg", msg),
-					slog.Any("result", result),
-				)
-			}
-
-			rsp := Message{
-				msgID:   msg.msgID,
-				msgType: MsgResult,
-				data:    data,
-			}
-
-			if err := svr.write(idx, &rsp); err != nil {
-				slog.Error(
-					"write message to handler failed",
-					slog.Any("error", err),
-				)
-			}
-
+			// 命令并发执行，避免耗时命令阻塞其他连接的命令
+			go svr.dispatch(svr.handlers[idx-1], msg, cmd)
 		}
 	}
 }
@@ -389,6 +565,8 @@ func (svr *CtlServer) StartLatencyClient(kwargs map[string]string) (*latency4go.
 	); err != nil {
 		return nil, err
 	}
+	client.SetHistorySize(svr.historySize)
+	svr.applyApprovals(client)
 
 	if err := client.Start(inter); err != nil {
 		return nil, err
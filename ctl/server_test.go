@@ -5,6 +5,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/frozenpine/latency4go"
 )
 
 func TestRangeSelect(t *testing.T) {
@@ -36,3 +38,37 @@ func TestRangeSelect(t *testing.T) {
 
 	t.Log(idx, v, ok, time.Since(start))
 }
+
+func TestApplyApprovals(t *testing.T) {
+	svr, err := NewCtlServer(
+		t.Context(), (&CtlSvrHdlConfig{}).Approval(time.Minute, "a", "b"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer svr.cancel()
+
+	report := func(*latency4go.State) error { return nil }
+
+	// 模拟 stop 后重新启动的 LatencyClient，仅 a 已注册
+	client := &latency4go.LatencyClient{}
+	if err := client.AddReporter("a", report); err != nil {
+		t.Fatal(err)
+	}
+
+	svr.applyApprovals(client)
+	if approvals := client.GetApprovals(); len(approvals) != 1 ||
+		approvals["a"] != time.Minute {
+		t.Fatalf("approvals not applied: %+v", approvals)
+	}
+
+	// 插件加载后设置其审批模式
+	if err := client.AddReporter("b", report); err != nil {
+		t.Fatal(err)
+	}
+
+	svr.applyApprovals(client, "b")
+	if approvals := client.GetApprovals(); len(approvals) != 2 {
+		t.Fatalf("loaded plugin approval not applied: %+v", approvals)
+	}
+}
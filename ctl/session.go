@@ -0,0 +1,107 @@
+package ctl
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionNotKick  = errors.New("session can not be kicked")
+)
+
+// SessionInfo 已连接客户端的会话信息
+type SessionInfo struct {
+	Handler       string
+	Remote        string
+	Identity      string              `json:",omitempty"`
+	Role          Role                `json:",omitzero"`
+	Connected     time.Time           `json:",omitzero"`
+	LastCommand   string              `json:",omitempty"`
+	LastActive    time.Time           `json:",omitzero"`
+	Subscriptions map[Topic]SubOption `json:",omitempty"`
+	Queue         *QueueStat          `json:",omitempty"`
+}
+
+// sessionCommand 会话最近一次执行的命令
+type sessionCommand struct {
+	name string
+	at   time.Time
+}
+
+// touch 记录会话最近一次执行的命令
+func (sess *session) touch(cmdName string) {
+	if sess == nil {
+		return
+	}
+
+	sess.lastCmd.Store(&sessionCommand{name: cmdName, at: time.Now()})
+}
+
+// Sessions 当前已连接的客户端会话，按连接时间排序
+func (hdl *ctlBaseHandler) Sessions() []SessionInfo {
+	sessions := []SessionInfo{}
+
+	hdl.hdlConnections.Range(func(key, value any) bool {
+		info := SessionInfo{
+			Handler: hdl.hdlName,
+			Remote:  fmt.Sprint(key),
+		}
+
+		if sw, ok := value.(sessionWriter); ok {
+			if sess := sw.getSession(); sess != nil {
+				info.Identity = sess.identity
+				info.Role = sess.role
+				info.Connected = sess.connected
+
+				if last := sess.lastCmd.Load(); last != nil {
+					info.LastCommand = last.name
+					info.LastActive = last.at
+				}
+
+				if sess.subs != nil {
+					info.Subscriptions = sess.subs.list()
+				}
+			}
+		}
+
+		if qw, ok := value.(queuedWriter); ok {
+			stat := qw.queueStats()
+			info.Queue = &stat
+		}
+
+		sessions = append(sessions, info)
+		return true
+	})
+
+	slices.SortFunc(sessions, func(a, b SessionInfo) int {
+		return a.Connected.Compare(b.Connected)
+	})
+
+	return sessions
+}
+
+// kickReason 通知被断开客户端的断开原因
+const kickReason = "session kicked by server"
+
+// Kick 断开指定会话的连接，连接由读协程负责移除，
+// 带发送队列的连接先通知断开原因，客户端据此放弃自动重连
+func (hdl *ctlBaseHandler) Kick(remote string) error {
+	value, exist := hdl.hdlConnections.Load(remote)
+	if !exist {
+		return fmt.Errorf("%w: %s", ErrSessionNotFound, remote)
+	}
+
+	if qw, ok := value.(queuedWriter); ok {
+		return qw.kick(kickReason)
+	}
+
+	wr, ok := value.(interface{ Close() error })
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSessionNotKick, remote)
+	}
+
+	return wr.Close()
+}
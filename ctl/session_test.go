@@ -0,0 +1,106 @@
+package ctl
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionKick(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	for range 2 {
+		client, err := NewCtlTcpClient(
+			hdl.listen.Addr().String() + "?reconnect=false",
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		client.Init(t.Context(), "test client", func() { go client.recv() })
+		defer client.Release()
+	}
+
+	sessions := hdl.Sessions()
+	if len(sessions) != 2 {
+		t.Fatalf("session count mismatch: %d", len(sessions))
+	}
+
+	for _, sess := range sessions {
+		if sess.Role != RoleAdmin || sess.Connected.IsZero() || sess.Queue == nil {
+			t.Fatalf("invalid session info: %+v", sess)
+		}
+	}
+
+	if err = hdl.Kick("127.0.0.1:1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("kick unknown session: %+v", err)
+	}
+
+	if err = hdl.Kick(sessions[0].Remote); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(time.Second * 5)
+	for hdl.ConnCount() != 1 {
+		select {
+		case <-deadline:
+			t.Fatalf("kicked session not removed: %d", hdl.ConnCount())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if remain := hdl.Sessions(); len(remain) != 1 ||
+		remain[0].Remote != sessions[1].Remote {
+		t.Fatalf("remain sessions mismatch: %+v", remain)
+	}
+}
+
+func TestSessionKickReconnect(t *testing.T) {
+	hdl, err := NewCtlTcpHandler("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	// 默认开启断线重连
+	client, err := NewCtlTcpClient(hdl.listen.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Init(t.Context(), "test client", func() { go client.recv() })
+	defer client.Release()
+
+	sessions := hdl.Sessions()
+	if len(sessions) != 1 {
+		t.Fatalf("session count mismatch: %d", len(sessions))
+	}
+
+	if err = hdl.Kick(sessions[0].Remote); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(time.Second * 5)
+	for client.GetConnState() != ConnClosed {
+		select {
+		case <-deadline:
+			t.Fatalf("kicked client not closed: %s", client.GetConnState())
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if reason := client.closed.Load(); reason == nil || reason.Reason != kickReason {
+		t.Fatalf("kick reason mismatch: %+v", reason)
+	}
+
+	// 超过重连退避时间后仍保持断开
+	time.Sleep(reconnectBackoffMin * 2)
+
+	if count := hdl.ConnCount(); count != 0 {
+		t.Fatalf("kicked client reconnected: %d", count)
+	}
+}
@@ -0,0 +1,115 @@
+package ctl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+var (
+	ErrTlsConfig = errors.New("invalid tls config")
+)
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w: no cert found in %s", ErrTlsConfig, caFile)
+	}
+
+	return pool, nil
+}
+
+// newServerTlsConfig 根据连接参数生成服务端 TLS 配置
+//
+//	cert: 服务端证书, key: 服务端私钥
+//	ca: 客户端证书签发 CA，指定后要求并校验客户端证书(mTLS)
+func newServerTlsConfig(opts url.Values) (*tls.Config, error) {
+	certFile, keyFile := opts.Get("cert"), opts.Get("key")
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("%w: no server cert or key", ErrTlsConfig)
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, errors.Join(ErrTlsConfig, err)
+	}
+
+	cfg := tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if caFile := opts.Get("ca"); caFile != "" {
+		if cfg.ClientCAs, err = loadCertPool(caFile); err != nil {
+			return nil, err
+		}
+
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return &cfg, nil
+}
+
+// newClientTlsConfig 根据连接参数生成客户端 TLS 配置
+//
+//	ca: 服务端证书签发 CA，未指定时使用系统 CA
+//	cert, key: 客户端证书及私钥，用于 mTLS
+//	name: 服务端证书名称，未指定时使用连接地址
+//	insecure: 跳过服务端证书校验，仅用于测试
+func newClientTlsConfig(addr string, opts url.Values) (*tls.Config, error) {
+	cfg := tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: opts.Get("name"),
+	}
+
+	if cfg.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, errors.Join(ErrTlsConfig, err)
+		}
+		cfg.ServerName = host
+	}
+
+	if caFile := opts.Get("ca"); caFile != "" {
+		pool, err := loadCertPool(caFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+
+	if certFile, keyFile := opts.Get("cert"), opts.Get("key"); certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Join(ErrTlsConfig, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if insecure, _ := strconv.ParseBool(opts.Get("insecure")); insecure {
+		cfg.InsecureSkipVerify = true
+	}
+
+	return &cfg, nil
+}
+
+// tlsPeerIdentity 返回已校验客户端证书的 CN 作为连接身份
+func tlsPeerIdentity(conn *tls.Conn) string {
+	state := conn.ConnectionState()
+
+	if len(state.VerifiedChains) <= 0 || len(state.PeerCertificates) <= 0 {
+		return ""
+	}
+
+	return state.PeerCertificates[0].Subject.CommonName
+}
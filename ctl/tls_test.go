@@ -0,0 +1,153 @@
+package ctl
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestCert(
+	t *testing.T, dir, name string, tmpl, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey,
+) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(
+		rand.Reader, tmpl, parent, &key.PublicKey, parentKey,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(
+		filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.WriteFile(
+		filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+		0600,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert, key
+}
+
+func TestMutualTlsIdentity(t *testing.T) {
+	dir := t.TempDir()
+	notAfter := time.Now().Add(time.Hour)
+
+	ca, caKey := writeTestCert(t, dir, "ca", &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}, nil, nil)
+
+	writeTestCert(t, dir, "server", &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "ctl server"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, caKey)
+
+	writeTestCert(t, dir, "client", &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "trader01"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     notAfter,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	svrOpts := url.Values{
+		"cert": {filepath.Join(dir, "server.crt")},
+		"key":  {filepath.Join(dir, "server.key")},
+		"ca":   {filepath.Join(dir, "ca.crt")},
+	}
+	hdl, err := NewCtlTlsHandler("127.0.0.1:0?" + svrOpts.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdl.Init(t.Context(), hdl.Name(), hdl.Start)
+	defer hdl.Release()
+
+	cliOpts := url.Values{
+		"ca":   {filepath.Join(dir, "ca.crt")},
+		"cert": {filepath.Join(dir, "client.crt")},
+		"key":  {filepath.Join(dir, "client.key")},
+	}
+	client, err := NewCtlTlsClient(
+		hdl.listen.Addr().String() + "?" + cliOpts.Encode(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.conn.Close()
+
+	// 握手完成后服务端才会注册连接
+	ping, err := newPingMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = client.writeMsg(ping); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second * 3)
+	for time.Now().Before(deadline) {
+		var identity string
+		hdl.hdlConnections.Range(func(key, value any) bool {
+			identity = value.(*tcpMsgWriter).identity
+			return false
+		})
+
+		if identity == "trader01" {
+			return
+		} else if identity != "" {
+			t.Fatalf("unexpected identity: %s", identity)
+		}
+
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	t.Fatal("tls client identity not registered")
+}
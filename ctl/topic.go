@@ -0,0 +1,274 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/frozenpine/latency4go"
+)
+
+var (
+	ErrInvalidTopic = errors.New("invalid topic")
+)
+
+// Topic 广播消息主题，连接通过 subscribe / unsubscribe 命令选择接收的主题
+type Topic string
+
+const (
+	// TopicState 完整的 State 广播，新连接默认订阅
+	TopicState Topic = "state"
+	// TopicTopK 仅包含前 K 个前置的 State 广播
+	TopicTopK Topic = "topk"
+	// TopicPlugin 插件加载、卸载事件
+	TopicPlugin Topic = "plugin"
+	// TopicAlert 服务端告警事件
+	TopicAlert Topic = "alert"
+	// TopicLog 服务端日志
+	TopicLog Topic = "log"
+	// TopicProposal 审批模式下前置顺序提案的生成及状态变化
+	TopicProposal Topic = "proposal"
+)
+
+const defaultTopK = 5
+
+var topics = []Topic{
+	TopicState, TopicTopK, TopicPlugin, TopicAlert, TopicLog, TopicProposal,
+}
+
+func ParseTopic(v string) (Topic, error) {
+	for _, topic := range topics {
+		if strings.EqualFold(v, string(topic)) {
+			return topic, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: %s", ErrInvalidTopic, v)
+}
+
+// SubOption 订阅参数
+type SubOption struct {
+	// K topk 主题保留的前置数量
+	K int `json:",omitempty"`
+	// Changed 仅在前置排序变化时推送 state / topk 主题
+	Changed bool `json:",omitempty"`
+	// Level log 主题推送的最低日志级别，默认为 INFO
+	Level slog.Level `json:",omitempty"`
+}
+
+func parseSubOption(topic Topic, kwargs map[string]string) (opt SubOption, err error) {
+	if v, exist := kwargs["k"]; exist {
+		if opt.K, err = strconv.Atoi(v); err != nil || opt.K <= 0 {
+			return opt, fmt.Errorf("%w: invalid k %s", ErrInvalidMsgData, v)
+		}
+	} else if topic == TopicTopK {
+		opt.K = defaultTopK
+	}
+
+	if v, exist := kwargs["changed"]; exist {
+		if opt.Changed, err = strconv.ParseBool(v); err != nil {
+			return opt, fmt.Errorf("%w: invalid changed %s", ErrInvalidMsgData, v)
+		}
+	}
+
+	if v, exist := kwargs["level"]; exist {
+		if err = opt.Level.UnmarshalText([]byte(v)); err != nil {
+			return opt, fmt.Errorf("%w: invalid level %s", ErrInvalidMsgData, v)
+		}
+	}
+
+	return
+}
+
+// Event 非 State 主题的广播事件
+type Event struct {
+	Topic     Topic
+	Timestamp time.Time
+	Data      json.RawMessage
+}
+
+// PluginEvent 插件事件，Action 为 load 或 unload
+type PluginEvent struct {
+	Name    string
+	Action  string
+	Message string `json:",omitempty"`
+}
+
+// Alert 服务端告警
+type Alert struct {
+	Level   slog.Level
+	Source  string
+	Message string
+}
+
+// GetEventData 解析事件内容
+func GetEventData[T PluginEvent | Alert | latency4go.Proposal](e *Event) (*T, error) {
+	if e == nil {
+		return nil, ErrInvalidMsgType
+	}
+
+	var v T
+	if err := json.Unmarshal(e.Data, &v); err != nil {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidMsgData, err)
+	}
+
+	return &v, nil
+}
+
+func newEventMessage(topic Topic, v any) (*Message, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	if data, err = json.Marshal(&Event{
+		Topic:     topic,
+		Timestamp: time.Now(),
+		Data:      data,
+	}); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		msgType: MsgEvent,
+		topic:   topic,
+		data:    data,
+	}, nil
+}
+
+type subscription struct {
+	SubOption
+
+	// 上次推送的前置排序，用于 Changed 过滤
+	last string
+}
+
+// subscriptions 会话的主题订阅表
+type subscriptions struct {
+	lock   sync.Mutex
+	topics map[Topic]*subscription
+}
+
+func newSubscriptions() *subscriptions {
+	return &subscriptions{
+		topics: map[Topic]*subscription{
+			TopicState: {},
+		},
+	}
+}
+
+func (subs *subscriptions) set(topic Topic, opt SubOption) {
+	subs.lock.Lock()
+	defer subs.lock.Unlock()
+
+	subs.topics[topic] = &subscription{SubOption: opt}
+}
+
+func (subs *subscriptions) del(topic Topic) {
+	subs.lock.Lock()
+	defer subs.lock.Unlock()
+
+	delete(subs.topics, topic)
+}
+
+func (subs *subscriptions) list() map[Topic]SubOption {
+	subs.lock.Lock()
+	defer subs.lock.Unlock()
+
+	result := make(map[Topic]SubOption, len(subs.topics))
+	for topic, sub := range subs.topics {
+		result[topic] = sub.SubOption
+	}
+
+	return result
+}
+
+// changed 记录本次推送的前置排序，返回是否需要推送
+func (sub *subscription) changed(addrList []string) bool {
+	current := strings.Join(addrList, ",")
+
+	if sub.Changed && current == sub.last {
+		return false
+	}
+
+	sub.last = current
+	return true
+}
+
+// filter 按订阅主题及参数转换广播消息，返回需写入连接的消息
+func (subs *subscriptions) filter(msg *Message) (result []*Message) {
+	subs.lock.Lock()
+	defer subs.lock.Unlock()
+
+	switch msg.msgType {
+	case MsgEvent:
+		if _, exist := subs.topics[msg.topic]; exist {
+			result = append(result, msg)
+		}
+
+		return
+	case MsgLog:
+		if sub, exist := subs.topics[TopicLog]; exist && msg.level >= sub.Level {
+			result = append(result, msg)
+		}
+
+		return
+	}
+
+	// 非 State 的广播（如服务端退出通知）推送至 State 订阅
+	if msg.state == nil {
+		if _, exist := subs.topics[TopicState]; exist {
+			result = append(result, msg)
+		}
+
+		return
+	}
+
+	if sub, exist := subs.topics[TopicState]; exist && sub.changed(msg.state.AddrList) {
+		result = append(result, msg)
+	}
+
+	if sub, exist := subs.topics[TopicTopK]; exist {
+		topK := newTopKState(msg.state, sub.K)
+
+		if !sub.changed(topK.AddrList) {
+			return
+		}
+
+		data, err := json.Marshal(topK)
+		if err != nil {
+			slog.Error(
+				"marshal topk state failed",
+				slog.Any("error", err),
+			)
+			return
+		}
+
+		result = append(result, &Message{
+			msgType: MsgBroadCast,
+			topic:   TopicTopK,
+			data:    data,
+			state:   topK,
+		})
+	}
+
+	return
+}
+
+func newTopKState(state *latency4go.State, k int) *latency4go.State {
+	topK := *state
+
+	if k > 0 && k < len(state.LatencyList) {
+		topK.LatencyList = state.LatencyList[:k]
+	}
+	if k > 0 && k < len(state.AddrList) {
+		topK.AddrList = state.AddrList[:k]
+	}
+
+	return &topK
+}
@@ -0,0 +1,45 @@
+package ctl
+
+import (
+	"testing"
+
+	"github.com/frozenpine/latency4go"
+)
+
+func TestSubscriptionFilter(t *testing.T) {
+	subs := newSubscriptions()
+	subs.del(TopicState)
+	subs.set(TopicTopK, SubOption{K: 2, Changed: true})
+	subs.set(TopicAlert, SubOption{})
+
+	newStateMsg := func(addrs ...string) *Message {
+		return &Message{
+			msgType: MsgBroadCast,
+			topic:   TopicState,
+			state:   &latency4go.State{AddrList: addrs},
+		}
+	}
+
+	for _, c := range []struct {
+		msg    *Message
+		expect int
+	}{
+		{newStateMsg("a", "b", "c"), 1},
+		// 前 2 个前置未变化
+		{newStateMsg("a", "b", "d"), 0},
+		{newStateMsg("b", "a", "d"), 1},
+		{&Message{msgType: MsgEvent, topic: TopicAlert}, 1},
+		{&Message{msgType: MsgEvent, topic: TopicPlugin}, 0},
+	} {
+		result := subs.filter(c.msg)
+		if len(result) != c.expect {
+			t.Fatalf("filter %+v: expect %d msgs, got %d", c.msg, c.expect, len(result))
+		}
+
+		for _, msg := range result {
+			if msg.topic == TopicTopK && len(msg.state.AddrList) != 2 {
+				t.Fatalf("invalid topk state: %+v", msg.state)
+			}
+		}
+	}
+}
@@ -0,0 +1,364 @@
+"use strict";
+
+const HISTORY_SIZE = 120;
+const COLORS = [
+  "#4caf50", "#2196f3", "#ff9800", "#e91e63", "#9c27b0",
+  "#00bcd4", "#cddc39", "#ff5722", "#795548", "#607d8b",
+];
+
+const ROLES = ["none", "viewer", "operator", "admin"];
+
+const $ = (sel) => document.querySelector(sel);
+
+const dashboard = {
+  state: null,
+  history: [],
+  token: sessionStorage.getItem("ctl-token") || "",
+  events: null,
+  streamSeq: 0,
+};
+
+function fmtNum(v) {
+  if (typeof v !== "number") return "-";
+  return Math.abs(v) >= 1000 ? v.toFixed(0) : v.toFixed(2);
+}
+
+function fmtDuration(ns) {
+  if (!ns) return "onetime";
+  const sec = ns / 1e9;
+  if (sec % 3600 === 0) return `${sec / 3600}h`;
+  if (sec % 60 === 0) return `${sec / 60}m`;
+  return `${sec}s`;
+}
+
+function showResult(result, isError) {
+  const view = $("#result");
+  view.textContent = typeof result === "string" ?
+    result : JSON.stringify(result, null, 2);
+  view.classList.toggle("error", !!isError);
+}
+
+async function command(method, path, body) {
+  const headers = {};
+  if (dashboard.token) headers["Authorization"] = `Bearer ${dashboard.token}`;
+  // 服务端拒绝未声明 JSON 内容类型的变更请求
+  if (method !== "GET") headers["Content-Type"] = "application/json";
+
+  const rsp = await fetch(path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  let result;
+  try {
+    result = await rsp.json();
+  } catch (e) {
+    result = { Rtn: 1, Message: `${rsp.status} ${rsp.statusText}` };
+  }
+
+  if (!rsp.ok || result.Rtn !== 0) {
+    showResult(result, true);
+    throw new Error(result.Message);
+  }
+
+  showResult(result);
+  return result;
+}
+
+function renderTopK() {
+  const state = dashboard.state;
+  const list = $("#topk-list");
+  list.innerHTML = "";
+  if (!state) return;
+
+  const k = parseInt($("#topk-n").value, 10) || 6;
+  state.LatencyList.slice(0, k).forEach((front) => {
+    const li = document.createElement("li");
+    li.textContent = `${front.FrontAddr}  (${fmtNum(front.Priority)})`;
+    list.appendChild(li);
+  });
+}
+
+function renderFronts() {
+  const state = dashboard.state;
+  const body = $("#fronts-table tbody");
+  body.innerHTML = "";
+  if (!state) return;
+
+  state.LatencyList.forEach((front, idx) => {
+    const percents = Object.keys(front.Percents || {})
+      .sort((l, r) => parseFloat(l) - parseFloat(r))
+      .map((k) => `${k}:${fmtNum(front.Percents[k])}`)
+      .join(" ");
+
+    const tr = document.createElement("tr");
+    [
+      idx + 1, front.FrontAddr, fmtNum(front.Priority),
+      fmtNum(front.MinLatency), fmtNum(front.AvgLatency),
+      fmtNum(front.MaxLatency), fmtNum(front.StdevLatency),
+      percents, front.DocCount,
+    ].forEach((v) => {
+      const td = document.createElement("td");
+      td.textContent = v;
+      tr.appendChild(td);
+    });
+    body.appendChild(tr);
+  });
+}
+
+function renderHistory() {
+  const svg = $("#history-chart");
+  const legend = $("#history-legend");
+  svg.innerHTML = "";
+  legend.innerHTML = "";
+
+  const history = dashboard.history;
+  if (history.length === 0) return;
+
+  const fronts = new Map();
+  let min = Infinity;
+  let max = -Infinity;
+
+  history.forEach((state, x) => {
+    state.LatencyList.forEach((front) => {
+      if (!fronts.has(front.FrontAddr)) fronts.set(front.FrontAddr, []);
+      fronts.get(front.FrontAddr).push([x, front.Priority]);
+      min = Math.min(min, front.Priority);
+      max = Math.max(max, front.Priority);
+    });
+  });
+
+  const width = 800;
+  const height = 260;
+  const span = max - min || 1;
+  const step = history.length > 1 ? width / (history.length - 1) : width;
+
+  // 仅绘制当前排名靠前的前置，避免图例过多
+  const current = dashboard.state ? dashboard.state.AddrList : [];
+  current.slice(0, COLORS.length).forEach((addr, idx) => {
+    const points = fronts.get(addr);
+    if (!points) return;
+
+    const line = document.createElementNS("http://www.w3.org/2000/svg", "polyline");
+    line.setAttribute("fill", "none");
+    line.setAttribute("stroke", COLORS[idx]);
+    line.setAttribute("stroke-width", "1.5");
+    line.setAttribute("vector-effect", "non-scaling-stroke");
+    line.setAttribute("points", points.map(([x, y]) =>
+      `${(x * step).toFixed(1)},${(height - 10 - (y - min) / span * (height - 20)).toFixed(1)}`,
+    ).join(" "));
+    svg.appendChild(line);
+
+    const item = document.createElement("span");
+    item.style.setProperty("--c", COLORS[idx]);
+    item.textContent = addr;
+    legend.appendChild(item);
+  });
+}
+
+function renderConfig() {
+  const view = $("#config-view");
+  view.innerHTML = "";
+  const state = dashboard.state;
+  if (!state) return;
+
+  const cfg = state.Config;
+  const items = {
+    TimeRange: JSON.stringify(cfg.TimeRange || {}),
+    Tick2Order: `${cfg.Tick2Order.From} ~ ${cfg.Tick2Order.To} ps`,
+    Aggregation: `size: ${cfg.AggSize}, least: ${cfg.AggCount}`,
+    Quantile: (cfg.Quantile || []).join(", "),
+    Users: (cfg.Users || []).join(", ") || "-",
+    SortBy: cfg.SortBy || "params.mid",
+  };
+
+  Object.entries(items).forEach(([k, v]) => {
+    const dt = document.createElement("dt");
+    dt.textContent = k;
+    const dd = document.createElement("dd");
+    dd.textContent = v;
+    view.append(dt, dd);
+  });
+}
+
+function renderList(sel, values) {
+  const list = $(sel);
+  list.innerHTML = "";
+  (values || []).forEach((v) => {
+    const li = document.createElement("li");
+    li.textContent = v;
+    list.appendChild(li);
+  });
+}
+
+function applyState(state) {
+  if (!state || !Array.isArray(state.LatencyList)) return;
+
+  dashboard.state = state;
+  dashboard.history.push(state);
+  if (dashboard.history.length > HISTORY_SIZE) dashboard.history.shift();
+
+  $("#update-ts").textContent =
+    `update: ${new Date(state.Timestamp).toLocaleString()}`;
+
+  renderTopK();
+  renderFronts();
+  renderHistory();
+  renderConfig();
+}
+
+function applyInfo(values) {
+  if (values.State) applyState(values.State);
+  if (values.Interval !== undefined) {
+    $("#interval").textContent = `interval: ${fmtDuration(values.Interval)}`;
+  }
+  renderList("#handler-list", values.Handlers);
+  renderList("#plugin-list", (values.Plugins || []).map(
+    (p) => `${p.Name} [${p.PluginType}] ${p.LibDir}`,
+  ));
+}
+
+async function refreshInfo() {
+  try {
+    const result = await command("GET", "api/info");
+    applyInfo(result.Values || {});
+  } catch (e) {
+    console.error("refresh info failed", e);
+  }
+}
+
+// streamUrl 返回推送连接地址，EventSource 无法设置请求头，
+// 登录令牌后以一次性票据代替令牌，避免令牌出现在访问日志及浏览器历史中
+async function streamUrl() {
+  if (!dashboard.token) return "api/events";
+
+  const rsp = await fetch("api/ticket", {
+    method: "POST",
+    headers: { "Authorization": `Bearer ${dashboard.token}` },
+  });
+  if (!rsp.ok) return "api/events";
+
+  const { Ticket } = await rsp.json();
+  return `api/events?ticket=${encodeURIComponent(Ticket)}`;
+}
+
+async function subscribe() {
+  const status = $("#conn-status");
+
+  if (dashboard.events) dashboard.events.close();
+  dashboard.events = null;
+  const seq = ++dashboard.streamSeq;
+
+  let url = "api/events";
+  try {
+    url = await streamUrl();
+  } catch (e) {
+    console.error("request stream ticket failed", e);
+  }
+  // 申请票据期间已重新订阅
+  if (seq !== dashboard.streamSeq) return;
+
+  const events = new EventSource(url);
+  dashboard.events = events;
+
+  events.onopen = () => {
+    status.textContent = "online";
+    status.className = "status online";
+  };
+
+  // 票据仅可使用一次，断线后重新申请票据建立连接，而非由 EventSource 自动重连
+  events.onerror = () => {
+    status.textContent = "offline";
+    status.className = "status offline";
+
+    events.close();
+    setTimeout(() => {
+      if (dashboard.events === events) subscribe();
+    }, 3000);
+  };
+
+  // 会话被服务端断开，不再重新订阅
+  events.addEventListener("close", () => {
+    dashboard.events = null;
+    events.close();
+    status.textContent = "kicked";
+    status.className = "status offline";
+  });
+
+  events.addEventListener("broadcast", (evt) => {
+    try {
+      const env = JSON.parse(evt.data);
+      applyState(env.Data);
+    } catch (e) {
+      console.error("invalid broadcast", e);
+    }
+  });
+}
+
+async function checkAuth() {
+  const status = $("#auth-status");
+  const headers = {};
+  if (dashboard.token) headers["Authorization"] = `Bearer ${dashboard.token}`;
+
+  const rsp = await fetch("api/auth", { headers });
+  let role = "viewer";
+  if (rsp.ok) {
+    try {
+      role = (await rsp.json()).Role || role;
+    } catch (e) {
+      console.error("invalid auth response", e);
+    }
+  }
+
+  status.textContent = rsp.ok ? role : "guest";
+  status.className = rsp.ok ? "status online" : "status offline";
+  document.querySelectorAll("[data-role]").forEach((btn) => {
+    btn.disabled = ROLES.indexOf(role) < ROLES.indexOf(btn.dataset.role);
+  });
+}
+
+function bindControls() {
+  $("#topk-n").addEventListener("change", renderTopK);
+
+  $("#auth-form").addEventListener("submit", (evt) => {
+    evt.preventDefault();
+    dashboard.token = $("#auth-token").value;
+    sessionStorage.setItem("ctl-token", dashboard.token);
+    checkAuth();
+    subscribe();
+  });
+
+  document.querySelectorAll("button[data-cmd]").forEach((btn) => {
+    btn.addEventListener("click", () => {
+      command("POST", `api/${btn.dataset.cmd}`).catch(() => {});
+    });
+  });
+
+  $("#period-form").addEventListener("submit", (evt) => {
+    evt.preventDefault();
+    const interval = new FormData(evt.target).get("interval");
+    command("PUT", `api/period?interval=${encodeURIComponent(interval)}`)
+      .then(refreshInfo).catch(() => {});
+  });
+
+  $("#config-form").addEventListener("submit", (evt) => {
+    evt.preventDefault();
+    const kwargs = {};
+    new FormData(evt.target).forEach((v, k) => {
+      if (v !== "") kwargs[k] = v;
+    });
+    if (Object.keys(kwargs).length === 0) return;
+    command("PUT", "api/config", kwargs).then((result) => {
+      if (dashboard.state && result.Values && result.Values.Config) {
+        dashboard.state.Config = result.Values.Config;
+        renderConfig();
+      }
+    }).catch(() => {});
+  });
+}
+
+bindControls();
+checkAuth();
+refreshInfo();
+subscribe();
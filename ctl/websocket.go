@@ -0,0 +1,289 @@
+package ctl
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// 最小化的 RFC 6455 服务端实现，仅支持文本/二进制消息及控制帧
+const (
+	wsOpContinuation byte = 0x0
+	wsOpText         byte = 0x1
+	wsOpBinary       byte = 0x2
+	wsOpClose        byte = 0x8
+	wsOpPing         byte = 0x9
+	wsOpPong         byte = 0xA
+
+	wsAcceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	// wsMaxFrameBytes 单个数据帧的最大长度，读取帧数据前校验
+	wsMaxFrameBytes = 1 << 20
+	// wsMaxMessageBytes 分片消息合并后的最大长度
+	wsMaxMessageBytes = 16 << 20
+	// wsMaxControlBytes 控制帧的最大长度
+	wsMaxControlBytes = 125
+
+	// 关闭帧状态码
+	wsCloseNormal   uint16 = 1000
+	wsCloseProtocol uint16 = 1002
+	wsCloseTooBig   uint16 = 1009
+)
+
+var (
+	ErrWsHandshake     = errors.New("websocket handshake failed")
+	ErrWsProtocol      = errors.New("websocket protocol error")
+	ErrWsMessageTooBig = errors.New("websocket message too big")
+	ErrWsClosed        = errors.New("websocket closed")
+)
+
+type wsConn struct {
+	conn   net.Conn
+	rd     *bufio.Reader
+	wrLock sync.Mutex
+	closed bool
+}
+
+func wsAcceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsAcceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for s := range strings.SplitSeq(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, fmt.Errorf("%w: not a websocket upgrade", ErrWsHandshake)
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("%w: unsupported version", ErrWsHandshake)
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, fmt.Errorf("%w: no websocket key", ErrWsHandshake)
+	}
+
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("%w: hijack not supported", ErrWsHandshake)
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, errors.Join(ErrWsHandshake, err)
+	}
+
+	if _, err = rw.WriteString(
+		"HTTP/1.1 101 Switching Protocols\r\n" +
+			"Upgrade: websocket\r\n" +
+			"Connection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + wsAcceptKey(key) + "\r\n\r\n",
+	); err == nil {
+		err = rw.Flush()
+	}
+
+	if err != nil {
+		conn.Close()
+		return nil, errors.Join(ErrWsHandshake, err)
+	}
+
+	return &wsConn{conn: conn, rd: rw.Reader}, nil
+}
+
+func (ws *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var header [2]byte
+	if _, err = io.ReadFull(ws.rd, header[:]); err != nil {
+		return
+	}
+
+	fin = header[0]&0x80 != 0
+	opcode = header[0] & 0x0F
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7F)
+
+	// 未协商扩展，RSV 位须为 0
+	if header[0]&0x70 != 0 {
+		err = fmt.Errorf("%w: reserved bits set", ErrWsProtocol)
+		return
+	}
+
+	if opcode >= wsOpClose && (!fin || length > wsMaxControlBytes) {
+		err = fmt.Errorf("%w: invalid control frame", ErrWsProtocol)
+		return
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(ws.rd, ext[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(ws.rd, ext[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if !masked {
+		err = fmt.Errorf("%w: client frame not masked", ErrWsProtocol)
+		return
+	}
+
+	if length > wsMaxFrameBytes {
+		err = ErrWsMessageTooBig
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.rd, mask[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.rd, payload); err != nil {
+		return
+	}
+
+	for idx := range payload {
+		payload[idx] ^= mask[idx%4]
+	}
+
+	return
+}
+
+// ReadMessage 读取一条完整的数据消息，控制帧在内部处理，
+// 协议错误或消息超长时以对应状态码发送关闭帧
+func (ws *wsConn) ReadMessage() (opcode byte, data []byte, err error) {
+	opcode, data, err = ws.readMessage()
+
+	switch {
+	case errors.Is(err, ErrWsProtocol):
+		ws.writeClose(wsCloseProtocol)
+	case errors.Is(err, ErrWsMessageTooBig):
+		ws.writeClose(wsCloseTooBig)
+	}
+
+	return
+}
+
+func (ws *wsConn) readMessage() (opcode byte, data []byte, err error) {
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch op {
+		case wsOpPing:
+			if err = ws.WriteMessage(wsOpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case wsOpPong:
+			continue
+		case wsOpClose:
+			// 回应关闭帧完成关闭握手，回应对端的状态码
+			code := wsCloseNormal
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			ws.writeClose(code)
+			return 0, nil, ErrWsClosed
+		case wsOpText, wsOpBinary:
+			if opcode != 0 {
+				return 0, nil, fmt.Errorf(
+					"%w: unexpected data frame", ErrWsProtocol,
+				)
+			}
+			opcode = op
+		case wsOpContinuation:
+			if opcode == 0 {
+				return 0, nil, fmt.Errorf(
+					"%w: unexpected continuation frame", ErrWsProtocol,
+				)
+			}
+		default:
+			return 0, nil, fmt.Errorf(
+				"%w: unknown opcode %d", ErrWsProtocol, op,
+			)
+		}
+
+		if len(data)+len(payload) > wsMaxMessageBytes {
+			return 0, nil, ErrWsMessageTooBig
+		}
+
+		data = append(data, payload...)
+
+		if fin {
+			return opcode, data, nil
+		}
+	}
+}
+
+func (ws *wsConn) WriteMessage(opcode byte, data []byte) error {
+	ws.wrLock.Lock()
+	defer ws.wrLock.Unlock()
+
+	if ws.closed {
+		return ErrWsClosed
+	}
+
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode
+
+	switch length := len(data); {
+	case length < 126:
+		header[1] = byte(length)
+	case length <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(length))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(length))
+	}
+
+	if _, err := ws.conn.Write(append(header, data...)); err != nil {
+		return err
+	}
+
+	if opcode == wsOpClose {
+		ws.closed = true
+	}
+
+	return nil
+}
+
+func (ws *wsConn) writeClose(code uint16) error {
+	return ws.WriteMessage(wsOpClose, binary.BigEndian.AppendUint16(nil, code))
+}
+
+func (ws *wsConn) Close() error {
+	ws.writeClose(wsCloseNormal)
+
+	return ws.conn.Close()
+}
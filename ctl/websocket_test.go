@@ -0,0 +1,307 @@
+package ctl
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFrame(t *testing.T, conn net.Conn, fin bool, opcode byte, data []byte) {
+	t.Helper()
+
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{opcode}
+	if fin {
+		frame[0] |= 0x80
+	}
+
+	switch {
+	case len(data) < 126:
+		frame = append(frame, 0x80|byte(len(data)))
+	case len(data) <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(data)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(data)))
+	}
+
+	frame = append(frame, mask[:]...)
+	for idx, b := range data {
+		frame = append(frame, b^mask[idx%4])
+	}
+
+	if _, err := conn.Write(frame); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeMaskedFrame(t *testing.T, conn net.Conn, opcode byte, data []byte) {
+	t.Helper()
+
+	writeFrame(t, conn, true, opcode, data)
+}
+
+// readServerFrame 读取服务端帧，服务端帧不带掩码
+func readServerFrame(t *testing.T, rd *bufio.Reader) (byte, []byte) {
+	t.Helper()
+
+	var header [2]byte
+	if _, err := io.ReadFull(rd, header[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(rd, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(rd, ext[:]); err != nil {
+			t.Fatal(err)
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(rd, data); err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0F, data
+}
+
+// dialWebSocket 完成 WebSocket 握手，返回连接及读取服务端帧的 Reader
+func dialWebSocket(t *testing.T, addr, path string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(addr, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	if _, err := conn.Write([]byte(
+		"GET " + path + " HTTP/1.1\r\nHost: test\r\n" +
+			"Upgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Key: " + key + "\r\n" +
+			"Sec-WebSocket-Version: 13\r\n\r\n",
+	)); err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+
+	rd := bufio.NewReader(conn)
+	rsp, err := http.ReadResponse(rd, nil)
+	if err != nil {
+		conn.Close()
+		t.Fatal(err)
+	}
+
+	if rsp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		t.Fatalf("unexpected status: %s", rsp.Status)
+	}
+
+	if accept := rsp.Header.Get(
+		"Sec-WebSocket-Accept",
+	); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		conn.Close()
+		t.Fatalf("invalid accept key: %s", accept)
+	}
+
+	return conn, rd
+}
+
+// newWsEchoServer 回显数据消息的 WebSocket 服务，读取结束的错误写入 errs
+func newWsEchoServer(t *testing.T) (*httptest.Server, <-chan error) {
+	t.Helper()
+
+	errs := make(chan error, 1)
+
+	svr := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			ws, err := upgradeWebSocket(w, r)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer ws.Close()
+
+			for {
+				op, data, err := ws.ReadMessage()
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if err := ws.WriteMessage(op, data); err != nil {
+					errs <- err
+					return
+				}
+			}
+		},
+	))
+
+	return svr, errs
+}
+
+func waitWsError(t *testing.T, errs <-chan error, target error) {
+	t.Helper()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, target) {
+			t.Fatalf("unexpected read error: %v, expect %v", err, target)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatal("wait websocket read error timeout")
+	}
+}
+
+func expectClose(t *testing.T, rd *bufio.Reader, code uint16) {
+	t.Helper()
+
+	op, data := readServerFrame(t, rd)
+	if op != wsOpClose || len(data) != 2 ||
+		binary.BigEndian.Uint16(data) != code {
+		t.Fatalf("unexpected close frame: %d, %v", op, data)
+	}
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	svr, _ := newWsEchoServer(t)
+	defer svr.Close()
+
+	conn, rd := dialWebSocket(t, svr.URL, "/")
+	defer conn.Close()
+
+	payload := bytes.Repeat([]byte(`{"MsgID":1}`), 20)
+	writeMaskedFrame(t, conn, wsOpPing, []byte("ping"))
+	writeMaskedFrame(t, conn, wsOpText, payload)
+
+	for {
+		switch op, data := readServerFrame(t, rd); op {
+		case wsOpPong:
+			if string(data) != "ping" {
+				t.Fatalf("invalid pong payload: %s", data)
+			}
+		case wsOpText:
+			if !bytes.Equal(data, payload) {
+				t.Fatalf("echo mismatch: %s", data)
+			}
+			return
+		default:
+			t.Fatalf("unexpected opcode: %d", op)
+		}
+	}
+}
+
+func TestWebSocketFragmentation(t *testing.T) {
+	svr, _ := newWsEchoServer(t)
+	defer svr.Close()
+
+	conn, rd := dialWebSocket(t, svr.URL, "/")
+	defer conn.Close()
+
+	// 分片之间的控制帧立即处理，不影响分片消息的合并
+	writeFrame(t, conn, false, wsOpText, []byte("hel"))
+	writeFrame(t, conn, true, wsOpPing, []byte("between"))
+	writeFrame(t, conn, false, wsOpContinuation, []byte("lo "))
+	writeFrame(t, conn, true, wsOpContinuation, []byte("world"))
+
+	if op, data := readServerFrame(t, rd); op != wsOpPong || string(data) != "between" {
+		t.Fatalf("unexpected control frame reply: %d, %s", op, data)
+	}
+
+	if op, data := readServerFrame(t, rd); op != wsOpText || string(data) != "hello world" {
+		t.Fatalf("fragmented message mismatch: %d, %s", op, data)
+	}
+}
+
+func TestWebSocketProtocolError(t *testing.T) {
+	for name, send := range map[string]func(*testing.T, net.Conn){
+		"fragmented control": func(t *testing.T, conn net.Conn) {
+			writeFrame(t, conn, false, wsOpPing, []byte("ping"))
+		},
+		"oversized control": func(t *testing.T, conn net.Conn) {
+			writeFrame(t, conn, true, wsOpPing, bytes.Repeat([]byte("x"), 126))
+		},
+		"orphan continuation": func(t *testing.T, conn net.Conn) {
+			writeFrame(t, conn, true, wsOpContinuation, []byte("x"))
+		},
+		"interleaved data": func(t *testing.T, conn net.Conn) {
+			writeFrame(t, conn, false, wsOpText, []byte("a"))
+			writeFrame(t, conn, true, wsOpText, []byte("b"))
+		},
+		"unmasked": func(t *testing.T, conn net.Conn) {
+			if _, err := conn.Write([]byte{0x80 | wsOpText, 1, 'x'}); err != nil {
+				t.Fatal(err)
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svr, errs := newWsEchoServer(t)
+			defer svr.Close()
+
+			conn, rd := dialWebSocket(t, svr.URL, "/")
+			defer conn.Close()
+
+			send(t, conn)
+
+			waitWsError(t, errs, ErrWsProtocol)
+			expectClose(t, rd, wsCloseProtocol)
+		})
+	}
+}
+
+func TestWebSocketTooBig(t *testing.T) {
+	svr, errs := newWsEchoServer(t)
+	defer svr.Close()
+
+	conn, rd := dialWebSocket(t, svr.URL, "/")
+	defer conn.Close()
+
+	// 仅发送帧头，超长的帧在读取数据前拒绝
+	header := []byte{0x80 | wsOpBinary, 0x80 | 127}
+	header = binary.BigEndian.AppendUint64(header, wsMaxFrameBytes+1)
+	header = append(header, 0, 0, 0, 0)
+	if _, err := conn.Write(header); err != nil {
+		t.Fatal(err)
+	}
+
+	waitWsError(t, errs, ErrWsMessageTooBig)
+	expectClose(t, rd, wsCloseTooBig)
+}
+
+func TestWebSocketCloseHandshake(t *testing.T) {
+	svr, errs := newWsEchoServer(t)
+	defer svr.Close()
+
+	conn, rd := dialWebSocket(t, svr.URL, "/")
+	defer conn.Close()
+
+	writeMaskedFrame(
+		t, conn, wsOpClose, binary.BigEndian.AppendUint16(nil, 1001),
+	)
+
+	// 服务端回应对端的状态码，其后不再发送数据帧
+	expectClose(t, rd, 1001)
+	waitWsError(t, errs, ErrWsClosed)
+
+	if _, err := rd.ReadByte(); !errors.Is(err, io.EOF) {
+		t.Fatalf("connection not closed after close handshake: %v", err)
+	}
+}
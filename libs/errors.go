@@ -10,4 +10,5 @@ var (
 	ErrReportFailed    = errors.New("report fronts failed")
 	ErrStopFailed      = errors.New("stop plugin failed")
 	ErrJoinFailed      = errors.New("join exit failed")
+	ErrPluginStopped   = errors.New("plugin stopped")
 )
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"runtime"
 	"strings"
@@ -15,8 +16,9 @@ import (
 type pluginType string
 
 const (
-	GoPlugin pluginType = "golib"
-	CPlugin  pluginType = "clib"
+	GoPlugin      pluginType = "golib"
+	CPlugin       pluginType = "clib"
+	BuiltinPlugin pluginType = "builtin"
 )
 
 var (
@@ -47,6 +49,60 @@ type PluginContainer struct {
 	pluginType pluginType
 	libDir     string
 	name       string
+
+	// guard 保护插件调用，Stop 持有写锁销毁插件，
+	// 其余调用持有读锁，避免插件销毁期间及销毁后的调用
+	guard   sync.RWMutex
+	stopped bool
+}
+
+// ReportFronts 经插件上报前置优先级，插件停止后返回 ErrPluginStopped
+func (c *PluginContainer) ReportFronts(addrList ...string) error {
+	c.guard.RLock()
+	defer c.guard.RUnlock()
+
+	if c.stopped {
+		return ErrPluginStopped
+	}
+
+	return c.Plugin.ReportFronts(addrList...)
+}
+
+// GetSeats 获取插件的交易系统席位，插件停止后返回 ErrPluginStopped
+func (c *PluginContainer) GetSeats() ([]Seat, error) {
+	c.guard.RLock()
+	defer c.guard.RUnlock()
+
+	if c.stopped {
+		return nil, ErrPluginStopped
+	}
+
+	return c.Plugin.Seats(), nil
+}
+
+// GetPriority 获取插件的交易系统优先级，插件停止后返回 ErrPluginStopped
+func (c *PluginContainer) GetPriority() ([][]int, error) {
+	c.guard.RLock()
+	defer c.guard.RUnlock()
+
+	if c.stopped {
+		return nil, ErrPluginStopped
+	}
+
+	return c.Plugin.Priority(), nil
+}
+
+// Stop 等待执行中的插件调用结束后销毁插件，重复调用时忽略
+func (c *PluginContainer) Stop() {
+	c.guard.Lock()
+	defer c.guard.Unlock()
+
+	if c.stopped {
+		return
+	}
+	c.stopped = true
+
+	c.Plugin.Stop()
 }
 
 func (c *PluginContainer) Name() string {
@@ -143,6 +199,25 @@ func NewPlugin(libDir, name string) (container *PluginContainer, err error) {
 	}
 }
 
+// RegisterPlugin 注册进程内实现的插件，name 已注册时返回错误
+func RegisterPlugin(name string, plugin Plugin) (*PluginContainer, error) {
+	container := &PluginContainer{
+		Plugin:     plugin,
+		pluginType: BuiltinPlugin,
+		name:       name,
+	}
+
+	if _, loaded := registeredPlugins.LoadOrStore(
+		name, container,
+	); loaded {
+		return nil, fmt.Errorf(
+			"%w: plugin already loaded", errLibOpenFailed,
+		)
+	}
+
+	return container, nil
+}
+
 func GetRegisteredPlugin(name string) (*PluginContainer, error) {
 	v, exist := registeredPlugins.Load(name)
 	if !exist {